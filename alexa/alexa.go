@@ -0,0 +1,230 @@
+/*
+Package alexa converts Alexa skill request and response envelopes into
+Chatbase messages and events.
+
+Launch and intent requests are recorded as user messages using the intent
+name, fallback intents are marked as not handled. Output speech of responses
+is recorded as the agent's reply. New sessions and ended sessions are
+recorded as events:
+
+	messages, events := alexa.Convert(client, requestEnvelope, responseEnvelope)
+	if err := alexa.Submit(ctx, messages, events); err != nil {
+		// handle error
+	}
+*/
+package alexa
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+// Request types sent to a skill
+const (
+	LaunchRequest       = "LaunchRequest"
+	IntentRequest       = "IntentRequest"
+	SessionEndedRequest = "SessionEndedRequest"
+)
+
+// Intents of the events recorded for the session lifecycle
+const (
+	SessionStartedIntent = "session_started"
+	SessionEndedIntent   = "session_ended"
+)
+
+// FallbackIntents contains the names of intents that mark
+// a request as not handled
+var FallbackIntents = []string{"AMAZON.FallbackIntent"}
+
+// RequestEnvelope is the envelope of a request sent to a skill
+type RequestEnvelope struct {
+	Version string   `json:"version"`
+	Session *Session `json:"session,omitempty"`
+	Context *Context `json:"context,omitempty"`
+	Request Request  `json:"request"`
+}
+
+// Session describes the session of a request
+type Session struct {
+	New         bool   `json:"new"`
+	SessionID   string `json:"sessionId"`
+	Application struct {
+		ApplicationID string `json:"applicationId"`
+	} `json:"application"`
+	User User `json:"user"`
+}
+
+// Context contains information about the device and the user
+type Context struct {
+	System struct {
+		User User `json:"user"`
+	} `json:"System"`
+}
+
+// User is the Amazon account using the skill
+type User struct {
+	UserID string `json:"userId"`
+}
+
+// Request is the request itself
+type Request struct {
+	Type      string  `json:"type"`
+	RequestID string  `json:"requestId"`
+	Timestamp string  `json:"timestamp"`
+	Locale    string  `json:"locale,omitempty"`
+	Intent    *Intent `json:"intent,omitempty"`
+	Reason    string  `json:"reason,omitempty"`
+}
+
+// Intent is the intent resolved by Alexa
+type Intent struct {
+	Name  string          `json:"name"`
+	Slots map[string]Slot `json:"slots,omitempty"`
+}
+
+// Slot is a single slot of an intent
+type Slot struct {
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
+}
+
+// ResponseEnvelope is the envelope of a skill's response
+type ResponseEnvelope struct {
+	Version           string                 `json:"version"`
+	SessionAttributes map[string]interface{} `json:"sessionAttributes,omitempty"`
+	Response          Response               `json:"response"`
+}
+
+// Response is the response returned by a skill
+type Response struct {
+	OutputSpeech     *OutputSpeech `json:"outputSpeech,omitempty"`
+	ShouldEndSession *bool         `json:"shouldEndSession,omitempty"`
+}
+
+// OutputSpeech is the speech returned by a skill
+type OutputSpeech struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+	SSML string `json:"ssml,omitempty"`
+}
+
+var ssmlTags = regexp.MustCompile(`<[^>]*>`)
+
+// PlainText returns the text of the speech, removing SSML markup
+func (o *OutputSpeech) PlainText() string {
+	if o.Type == "SSML" {
+		return strings.TrimSpace(ssmlTags.ReplaceAllString(o.SSML, ""))
+	}
+	return o.Text
+}
+
+// UserID returns the id of the user making the request
+func (r *RequestEnvelope) UserID() string {
+	if r.Session != nil && r.Session.User.UserID != "" {
+		return r.Session.User.UserID
+	}
+	if r.Context != nil {
+		return r.Context.System.User.UserID
+	}
+	return ""
+}
+
+// SessionID returns the id of the request's session, if any
+func (r *RequestEnvelope) SessionID() string {
+	if r.Session != nil {
+		return r.Session.SessionID
+	}
+	return ""
+}
+
+// IntentName returns the name of the requested intent. Launch and session
+// ended requests use their request type as intent name
+func (r *RequestEnvelope) IntentName() string {
+	if r.Request.Intent != nil {
+		return r.Request.Intent.Name
+	}
+	return r.Request.Type
+}
+
+// Convert maps a request and its response to messages and events. Launch and
+// intent requests result in a user message, the response (which may be nil)
+// in an agent message. Session lifecycle changes result in events
+func Convert(client *chatbase.Client, req *RequestEnvelope, res *ResponseEnvelope) (chatbase.Messages, chatbase.Events) {
+	messages := chatbase.Messages{}
+	events := chatbase.Events{}
+	ts := timeStamp(req.Request.Timestamp)
+	userID, session, intent := req.UserID(), req.SessionID(), req.IntentName()
+
+	if req.Session != nil && req.Session.New {
+		events.Append(lifecycleEvent(client, userID, SessionStartedIntent, ts))
+	}
+
+	switch req.Request.Type {
+	case LaunchRequest, IntentRequest:
+		messages.Append(client.UserMessage(userID, chatbase.PlatformAlexa).
+			SetIntent(intent).
+			SetNotHandled(isFallback(intent)).
+			SetSessionID(session).
+			SetTimeStamp(ts))
+	case SessionEndedRequest:
+		ended := lifecycleEvent(client, userID, SessionEndedIntent, ts)
+		if req.Request.Reason != "" {
+			ended.AddProperty("reason", req.Request.Reason)
+		}
+		events.Append(ended)
+	}
+
+	if res != nil && res.Response.OutputSpeech != nil {
+		messages.Append(client.AgentMessage(userID, chatbase.PlatformAlexa).
+			SetMessage(res.Response.OutputSpeech.PlainText()).
+			SetIntent(intent).
+			SetSessionID(session))
+	}
+	return messages, events
+}
+
+// Submit delivers the given messages and events to Chatbase
+// while considering the given context's deadline
+func Submit(ctx context.Context, messages chatbase.Messages, events chatbase.Events) error {
+	if len(messages) > 0 {
+		res, err := messages.SubmitWithContext(ctx)
+		if err != nil {
+			return err
+		}
+		if !res.Status.OK() {
+			return fmt.Errorf("submitting messages failed: %s", res.Reason)
+		}
+	}
+	if len(events) > 0 {
+		return events.SubmitWithContext(ctx)
+	}
+	return nil
+}
+
+func lifecycleEvent(client *chatbase.Client, userID, intent string, ts int64) *chatbase.Event {
+	return client.Event(userID, intent).SetPlatform(chatbase.PlatformAlexa).SetTimeStamp(ts)
+}
+
+func isFallback(intent string) bool {
+	for _, fallback := range FallbackIntents {
+		if intent == fallback {
+			return true
+		}
+	}
+	return false
+}
+
+// timeStamp parses the request's ISO 8601 timestamp, falling
+// back to the current time if it cannot be parsed
+func timeStamp(value string) int64 {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return chatbase.TimeStamp()
+	}
+	return t.UnixNano() / 1e6
+}
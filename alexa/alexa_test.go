@@ -0,0 +1,140 @@
+package alexa
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (r roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r(req)
+}
+
+func decode(t *testing.T, s string, v interface{}) {
+	if err := json.Unmarshal([]byte(s), v); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+}
+
+func TestOutputSpeech_PlainText(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    OutputSpeech
+		expected string
+	}{
+		{"plain", OutputSpeech{Type: "PlainText", Text: "Hello there"}, "Hello there"},
+		{"ssml", OutputSpeech{Type: "SSML", SSML: `<speak>Hello <break time="1s"/>there</speak>`}, "Hello there"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if s := test.input.PlainText(); s != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, s)
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	oldTimeStamp := chatbase.TimeStamp
+	defer func() { chatbase.TimeStamp = oldTimeStamp }()
+	chatbase.TimeStamp = func() int64 { return 998877 }
+	client := chatbase.New("key")
+
+	session := `"session":{"new":%s,"sessionId":"s-1","application":{"applicationId":"app"},"user":{"userId":"u-1"}}`
+	tests := []struct {
+		name             string
+		request          string
+		response         string
+		expectedMessages chatbase.Messages
+		expectedEvents   chatbase.Events
+	}{
+		{
+			"launch",
+			`{"version":"1.0",` + strings.Replace(session, "%s", "true", 1) + `,"request":{"type":"LaunchRequest","requestId":"r","timestamp":"2018-01-02T15:04:05Z"}}`,
+			`{"version":"1.0","response":{"outputSpeech":{"type":"PlainText","text":"Welcome!"}}}`,
+			chatbase.Messages{
+				{APIKey: "key", Type: chatbase.UserType, UserID: "u-1", TimeStamp: 1514905445000, Platform: "Alexa", Intent: "LaunchRequest", SessionID: "s-1"},
+				{APIKey: "key", Type: chatbase.AgentType, UserID: "u-1", TimeStamp: 998877, Platform: "Alexa", Message: "Welcome!", Intent: "LaunchRequest", SessionID: "s-1"},
+			},
+			chatbase.Events{
+				{APIKey: "key", UserID: "u-1", Intent: "session_started", TimeStamp: 1514905445000, Platform: "Alexa"},
+			},
+		},
+		{
+			"fallback intent",
+			`{"version":"1.0",` + strings.Replace(session, "%s", "false", 1) + `,"request":{"type":"IntentRequest","requestId":"r","timestamp":"2018-01-02T15:04:05Z","intent":{"name":"AMAZON.FallbackIntent"}}}`,
+			"",
+			chatbase.Messages{
+				{APIKey: "key", Type: chatbase.UserType, UserID: "u-1", TimeStamp: 1514905445000, Platform: "Alexa", Intent: "AMAZON.FallbackIntent", NotHandled: true, SessionID: "s-1"},
+			},
+			chatbase.Events{},
+		},
+		{
+			"session ended",
+			`{"version":"1.0",` + strings.Replace(session, "%s", "false", 1) + `,"request":{"type":"SessionEndedRequest","requestId":"r","timestamp":"bad","reason":"USER_INITIATED"}}`,
+			"",
+			chatbase.Messages{},
+			chatbase.Events{
+				{APIKey: "key", UserID: "u-1", Intent: "session_ended", TimeStamp: 998877, Platform: "Alexa", Properties: []chatbase.EventProperty{{Name: "reason", StringValue: "USER_INITIATED"}}},
+			},
+		},
+		{
+			"without session",
+			`{"version":"1.0","context":{"System":{"user":{"userId":"u-2"}}},"request":{"type":"IntentRequest","requestId":"r","timestamp":"2018-01-02T15:04:05Z","intent":{"name":"PlayIntent"}}}`,
+			"",
+			chatbase.Messages{
+				{APIKey: "key", Type: chatbase.UserType, UserID: "u-2", TimeStamp: 1514905445000, Platform: "Alexa", Intent: "PlayIntent"},
+			},
+			chatbase.Events{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var req RequestEnvelope
+			decode(t, test.request, &req)
+			var res *ResponseEnvelope
+			if test.response != "" {
+				res = &ResponseEnvelope{}
+				decode(t, test.response, res)
+			}
+			messages, events := Convert(client, &req, res)
+			if !reflect.DeepEqual(test.expectedMessages, messages) {
+				t.Errorf("Expected %#v, got %#v", test.expectedMessages, messages)
+			}
+			if !reflect.DeepEqual(test.expectedEvents, events) {
+				t.Errorf("Expected %#v, got %#v", test.expectedEvents, events)
+			}
+		})
+	}
+}
+
+func TestSubmit(t *testing.T) {
+	defer chatbase.SetAPITransport(nil)
+	var paths []string
+	chatbase.SetAPITransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		paths = append(paths, r.URL.Path)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"all_succeeded":true,"status":200}`)),
+		}, nil
+	}))
+	client := chatbase.New("key")
+	messages := chatbase.Messages{}
+	messages.Append(client.UserMessage("u", chatbase.PlatformAlexa))
+	events := chatbase.Events{}
+	events.Append(client.Event("u", SessionStartedIntent))
+	if err := Submit(context.Background(), messages, events); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if expected := []string{"/api/messages", "/apis/v1/events/insert_batch"}; !reflect.DeepEqual(expected, paths) {
+		t.Errorf("Expected %v, got %v", expected, paths)
+	}
+}
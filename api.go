@@ -2,9 +2,12 @@ package chatbase
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"time"
@@ -26,33 +29,200 @@ func SetAPITimeout(t time.Duration) {
 	client.Timeout = t
 }
 
-func apiCall(method, endpoint string, v interface{}) (io.ReadCloser, error) {
-	payload, payloadErr := json.Marshal(v)
-	if payloadErr != nil {
-		return nil, payloadErr
-	}
+// call describes a single request made to the Chatbase API
+type call struct {
+	kind     string
+	method   string
+	endpoint string
+	items    int
+	// sent is true when the request has been passed to the http.Client,
+	// i.e. it has neither failed before nor been answered by an interceptor
+	sent       bool
+	statusCode int
+	duration   time.Duration
+	status     *Status
+	reason     string
+	failures   []string
+}
 
-	req, reqErr := http.NewRequest(method, endpoint, bytes.NewBuffer(payload))
-	if reqErr != nil {
-		return nil, reqErr
-	}
-	req.Header.Set("Content-Type", "application/json")
-	res, err := client.Do(req)
+func apiCall(ctx context.Context, cfg *config, method, endpoint string, v interface{}) (result io.ReadCloser, err error) {
+	kind, _ := describePayload(v)
+	var withheld []string
+	v, withheld, err = cfg.gate(ctx, v)
 	if err != nil {
 		return nil, err
 	}
+	if v == nil {
+		return ioutil.NopCloser(bytes.NewReader(withheldBody(kind, withheld))), nil
+	}
+	kind, count := describePayload(v)
+	info := &call{kind: kind, method: method, endpoint: endpoint, items: count}
+	defer func() { cfg.observe(ctx, info, err) }()
+
+	req := &APIRequest{
+		Method:   method,
+		Endpoint: endpoint,
+		Kind:     kind,
+		Items:    count,
+		Payload:  v,
+		Header:   http.Header{"Content-Type": {"application/json"}},
+	}
+	sent := new(bool)
+	ctx = context.WithValue(ctx, sentKey{}, sent)
+	start := time.Now()
+	defer func() { info.duration, info.sent = time.Since(start), *sent }()
+	res, resErr := cfg.chain()(ctx, req)
+	info.endpoint = req.Endpoint
+	if resErr != nil {
+		return nil, resErr
+	}
+	info.statusCode = res.StatusCode
 	if res.StatusCode >= http.StatusInternalServerError {
 		return nil, fmt.Errorf("request failed with status %v", res.StatusCode)
 	}
-	return res.Body, nil
+
+	var status struct {
+		Status    *Status `json:"status"`
+		Reason    string  `json:"reason"`
+		Responses []struct {
+			Status Status `json:"status"`
+			Reason string `json:"reason"`
+		} `json:"responses"`
+	}
+	if json.Unmarshal(res.Body, &status) == nil {
+		info.status, info.reason = status.Status, status.Reason
+		for _, item := range status.Responses {
+			if !item.Status.OK() {
+				info.failures = append(info.failures, item.Reason)
+			}
+		}
+	}
+	return ioutil.NopCloser(bytes.NewReader(spliceWithheld(res.Body, withheld))), nil
+}
+
+// withheldResponse is the result reported for an item that has been
+// withheld by the given pipeline, in the format used by the Chatbase API
+func withheldResponse(pipeline string) interface{} {
+	return map[string]interface{}{
+		"status":   http.StatusOK,
+		"reason":   "withheld by " + pipeline,
+		"withheld": pipeline,
+	}
+}
+
+// withheldBody returns the body reported in place of a response when all
+// items of a payload of the given kind have been withheld
+func withheldBody(kind string, withheld []string) []byte {
+	responses := make([]interface{}, len(withheld))
+	for i, pipeline := range withheld {
+		responses[i] = withheldResponse(pipeline)
+	}
+	var v interface{} = map[string]interface{}{
+		"all_succeeded": true,
+		"status":        http.StatusOK,
+		"responses":     responses,
+	}
+	switch kind {
+	case "message", "facebook_message", "facebook_request_response":
+		v = responses[0]
+	}
+	b, _ := json.Marshal(v)
+	return b
 }
 
-func apiPost(endpoint string, v interface{}) (io.ReadCloser, error) {
-	return apiCall(http.MethodPost, endpoint, v)
+// spliceWithheld inserts the results of withheld items into the responses
+// of a collection's body so they are aligned with the submitted items. The
+// body is returned unchanged if it does not contain a result for each
+// submitted item
+func spliceWithheld(body []byte, withheld []string) []byte {
+	if len(withheld) == 0 {
+		return body
+	}
+	var fields map[string]json.RawMessage
+	var submitted []json.RawMessage
+	if json.Unmarshal(body, &fields) != nil || json.Unmarshal(fields["responses"], &submitted) != nil {
+		return body
+	}
+	responses := make([]interface{}, 0, len(withheld))
+	for _, pipeline := range withheld {
+		if pipeline != "" {
+			responses = append(responses, withheldResponse(pipeline))
+			continue
+		}
+		if len(submitted) == 0 {
+			return body
+		}
+		responses = append(responses, submitted[0])
+		submitted = submitted[1:]
+	}
+	if len(submitted) != 0 {
+		return body
+	}
+	b, err := json.Marshal(responses)
+	if err != nil {
+		return body
+	}
+	fields["responses"] = b
+	if b, err = json.Marshal(fields); err != nil {
+		return body
+	}
+	return b
+}
+
+// describePayload returns the kind of endpoint a payload is sent
+// to and the number of items it contains
+func describePayload(v interface{}) (string, int) {
+	switch p := v.(type) {
+	case *Message:
+		return "message", 1
+	case *Messages:
+		return "messages", len(*p)
+	case *Event:
+		return "event", 1
+	case *Events:
+		return "events", len(*p)
+	case *FacebookMessage:
+		return "facebook_message", 1
+	case *FacebookMessages:
+		return "facebook_messages", len(*p)
+	case *FacebookRequestResponse:
+		return "facebook_request_response", 1
+	case *FacebookRequestResponses:
+		return "facebook_request_responses", len(*p)
+	case *Update:
+		return "update", 1
+	case *Link:
+		return "click", 1
+	}
+	return fmt.Sprintf("%T", v), 1
+}
+
+func apiPost(ctx context.Context, cfg *config, endpoint string, v interface{}) (io.ReadCloser, error) {
+	return apiCall(ctx, cfg, http.MethodPost, endpoint, v)
+}
+
+// errMixedConfig is returned when submitting a collection containing items
+// that have been created by different clients
+var errMixedConfig = errors.New("cannot submit collection of items created by different clients")
+
+// collectionConfig returns the configuration shared by the n items of a
+// collection. Items that have not been created by a client share a nil
+// configuration, so mixing them with items created by a client fails as well
+func collectionConfig(n int, itemConfig func(i int) *config) (*config, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	cfg := itemConfig(0)
+	for i := 1; i < n; i++ {
+		if itemConfig(i) != cfg {
+			return nil, errMixedConfig
+		}
+	}
+	return cfg, nil
 }
 
-func apiPut(endpoint string, v interface{}) (io.ReadCloser, error) {
-	return apiCall(http.MethodPut, endpoint, v)
+func apiPut(ctx context.Context, cfg *config, endpoint string, v interface{}) (io.ReadCloser, error) {
+	return apiCall(ctx, cfg, http.MethodPut, endpoint, v)
 }
 
 func newMessageResponse(thunk func() (io.ReadCloser, error)) (*MessageResponse, error) {
@@ -2,6 +2,7 @@ package chatbase
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"io/ioutil"
@@ -284,7 +285,7 @@ func TestApiCall(t *testing.T) {
 			if test.urlOverride != "" {
 				endpoint = test.urlOverride
 			}
-			res, err := apiCall(test.method, endpoint, test.data)
+			res, err := apiCall(context.Background(), nil, test.method, endpoint, test.data)
 			if test.expectError != (err != nil) {
 				t.Errorf("Unexpected error %v", err)
 			}
@@ -311,7 +312,7 @@ func TestApiPost(t *testing.T) {
 			}
 			w.Write([]byte("OK!"))
 		}))
-		res, err := apiPost(ts.URL, map[string]string{})
+		res, err := apiPost(context.Background(), nil, ts.URL, map[string]string{})
 		if err != nil {
 			t.Fatalf("Unexpected error %v", err)
 		}
@@ -333,7 +334,7 @@ func TestApiPut(t *testing.T) {
 			}
 			w.Write([]byte("OK!"))
 		}))
-		res, err := apiPut(ts.URL, map[string]string{})
+		res, err := apiPut(context.Background(), nil, ts.URL, map[string]string{})
 		if err != nil {
 			t.Fatalf("Unexpected error %v", err)
 		}
@@ -346,3 +347,52 @@ func TestApiPut(t *testing.T) {
 		}
 	})
 }
+
+func TestCollectionConfig(t *testing.T) {
+	requests := 0
+	SetAPITransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(`{"all_succeeded":true,"status":200}`))}, nil
+	}))
+	defer SetAPITransport(nil)
+
+	first, second := New("key", WithVersion("1.0.0")), New("key", WithVersion("2.0.0"))
+	submissions := map[string]func() error{
+		"messages": func() error {
+			_, err := (&Messages{}).Append(first.UserMessage("user", PlatformWeb), second.UserMessage("user", PlatformWeb)).Submit()
+			return err
+		},
+		"missing": func() error {
+			_, err := (&Messages{}).Append(first.UserMessage("user", PlatformWeb), &Message{APIKey: "key"}).Submit()
+			return err
+		},
+		"events": func() error {
+			return (&Events{}).Append(first.Event("user", "signup"), second.Event("user", "signup")).Submit()
+		},
+		"facebook messages": func() error {
+			_, err := (&FacebookMessages{}).Append(first.FacebookMessage(nil), second.FacebookMessage(nil)).Submit()
+			return err
+		},
+		"facebook pairs": func() error {
+			_, err := (&FacebookRequestResponses{}).Append(first.FacebookRequestResponse(nil, nil), second.FacebookRequestResponse(nil, nil)).Submit()
+			return err
+		},
+	}
+	for name, submit := range submissions {
+		t.Run(name, func(t *testing.T) {
+			if err := submit(); err != errMixedConfig {
+				t.Errorf("Expected errMixedConfig, got %v", err)
+			}
+		})
+	}
+	if requests != 0 {
+		t.Errorf("Expected no requests, got %d", requests)
+	}
+
+	if _, err := (&Messages{}).Append(first.UserMessage("a", PlatformWeb), first.UserMessage("b", PlatformWeb)).Submit(); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if _, err := (&Messages{}).Append(&Message{APIKey: "key"}, &Message{APIKey: "key"}).Submit(); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
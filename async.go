@@ -0,0 +1,78 @@
+package chatbase
+
+import "fmt"
+
+// SubmitAsync submits the given payloads one after the other in a new
+// goroutine, so handlers can respond without waiting for Chatbase. Payloads
+// can be messages, events or Facebook messages and their collections, nil
+// payloads and empty collections are skipped. Errors, including submissions
+// rejected by Chatbase, are passed to onError if it is not nil
+func SubmitAsync(onError func(error), payloads ...interface{}) {
+	go func() {
+		for _, payload := range payloads {
+			if err := submitPayload(payload); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}()
+}
+
+func submitPayload(v interface{}) error {
+	switch p := v.(type) {
+	case *Message:
+		if p == nil {
+			return nil
+		}
+		res, err := p.Submit()
+		if err != nil || res == nil {
+			return err
+		}
+		return rejected("message", res.Status, res.Reason)
+	case *Messages:
+		if p == nil || len(*p) == 0 {
+			return nil
+		}
+		res, err := p.Submit()
+		if err != nil || res == nil {
+			return err
+		}
+		return rejected("messages", res.Status, res.Reason)
+	case *FacebookMessage:
+		if p == nil {
+			return nil
+		}
+		res, err := p.Submit()
+		if err != nil || res == nil {
+			return err
+		}
+		return rejected("facebook message", res.Status, res.Reason)
+	case *FacebookMessages:
+		if p == nil || len(*p) == 0 {
+			return nil
+		}
+		res, err := p.Submit()
+		if err != nil || res == nil {
+			return err
+		}
+		return rejected("facebook messages", res.Status, res.Reason)
+	case *Event:
+		if p == nil {
+			return nil
+		}
+		return p.Submit()
+	case *Events:
+		if p == nil || len(*p) == 0 {
+			return nil
+		}
+		return p.Submit()
+	}
+	return fmt.Errorf("cannot submit payload of type %T", v)
+}
+
+// rejected returns an error for submissions that have been rejected by Chatbase
+func rejected(kind string, status Status, reason string) error {
+	if status.OK() {
+		return nil
+	}
+	return fmt.Errorf("submitting %s failed: %s", kind, reason)
+}
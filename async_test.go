@@ -0,0 +1,60 @@
+package chatbase
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSubmitAsync(t *testing.T) {
+	oldMessage, oldEvents := messageEndpoint, eventsEndpoint
+	defer func() { messageEndpoint, eventsEndpoint = oldMessage, oldEvents }()
+
+	requests := make(chan string, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r.URL.Path
+		if r.URL.Path == "/rejected" {
+			w.Write([]byte(`{"status":400,"reason":"missing platform"}`))
+			return
+		}
+		w.Write([]byte(`{"status":200}`))
+	}))
+	defer ts.Close()
+	messageEndpoint, eventsEndpoint = ts.URL+"/rejected", ts.URL+"/events"
+
+	errs := make(chan error, 10)
+	client := New("key")
+	events := Events{}
+	events.Append(client.Event("user", "signup"))
+	var message *Message
+	SubmitAsync(func(err error) { errs <- err }, client.UserMessage("user", ""), message, &Messages{}, &events, "unknown")
+
+	for _, expected := range []string{"/rejected", "/events"} {
+		select {
+		case path := <-requests:
+			if path != expected {
+				t.Errorf("Expected request to %v, got %v", expected, path)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected request to %v", expected)
+		}
+	}
+	for _, expected := range []string{"submitting message failed: missing platform", "cannot submit payload of type string"} {
+		select {
+		case err := <-errs:
+			if err.Error() != expected {
+				t.Errorf("Expected error %q, got %v", expected, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected error %q", expected)
+		}
+	}
+	select {
+	case path := <-requests:
+		t.Errorf("Unexpected request to %v", path)
+	case err := <-errs:
+		t.Errorf("Unexpected error %v", err)
+	default:
+	}
+}
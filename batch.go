@@ -0,0 +1,104 @@
+package chatbase
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// batcher collects items in a bounded queue and hands them to its flush
+// func in groups of at most size items, or whenever interval has passed.
+// Items that are added while the queue is full are dropped.
+type batcher struct {
+	queue    chan interface{}
+	size     int
+	interval time.Duration
+	flush    func([]interface{})
+	dropped  uint64
+	// mu guards closed so no item is enqueued after the queue has been
+	// drained on close
+	mu     sync.RWMutex
+	closed bool
+	stop   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+func newBatcher(capacity, size int, interval time.Duration, flush func([]interface{})) *batcher {
+	b := &batcher{
+		queue:    make(chan interface{}, capacity),
+		size:     size,
+		interval: interval,
+		flush:    flush,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// add enqueues the item, returning false if it has been dropped
+func (b *batcher) add(v interface{}) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		atomic.AddUint64(&b.dropped, 1)
+		return false
+	}
+	select {
+	case b.queue <- v:
+		return true
+	default:
+		atomic.AddUint64(&b.dropped, 1)
+		return false
+	}
+}
+
+// close flushes all pending items and stops the batcher
+func (b *batcher) close() {
+	b.once.Do(func() {
+		b.mu.Lock()
+		b.closed = true
+		b.mu.Unlock()
+		close(b.stop)
+	})
+	<-b.done
+}
+
+func (b *batcher) run() {
+	defer close(b.done)
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	var pending []interface{}
+	send := func() {
+		if len(pending) > 0 {
+			b.flush(pending)
+			pending = nil
+		}
+	}
+	for {
+		select {
+		case v := <-b.queue:
+			pending = append(pending, v)
+			if len(pending) >= b.size {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case <-b.stop:
+			for {
+				select {
+				case v := <-b.queue:
+					pending = append(pending, v)
+					if len(pending) >= b.size {
+						send()
+					}
+				default:
+					send()
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package chatbase
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBatcher(t *testing.T) {
+	t.Run("size", func(t *testing.T) {
+		var mu sync.Mutex
+		var batches [][]interface{}
+		b := newBatcher(10, 2, time.Hour, func(items []interface{}) {
+			mu.Lock()
+			defer mu.Unlock()
+			batches = append(batches, items)
+		})
+		for i := 0; i < 5; i++ {
+			if !b.add(i) {
+				t.Errorf("Unexpected drop of item %d", i)
+			}
+		}
+		b.close()
+		expected := [][]interface{}{{0, 1}, {2, 3}, {4}}
+		if !reflect.DeepEqual(expected, batches) {
+			t.Errorf("Expected %v, got %v", expected, batches)
+		}
+		if b.add(5) {
+			t.Error("Expected item to be dropped after closing")
+		}
+	})
+	t.Run("interval", func(t *testing.T) {
+		flushed := make(chan []interface{}, 1)
+		b := newBatcher(10, 100, 10*time.Millisecond, func(items []interface{}) {
+			flushed <- items
+		})
+		defer b.close()
+		b.add("a")
+		select {
+		case items := <-flushed:
+			if !reflect.DeepEqual([]interface{}{"a"}, items) {
+				t.Errorf("Unexpected items %v", items)
+			}
+		case <-time.After(time.Second):
+			t.Error("Expected items to be flushed")
+		}
+	})
+	t.Run("full queue", func(t *testing.T) {
+		block := make(chan struct{})
+		b := newBatcher(1, 1, time.Hour, func(items []interface{}) {
+			<-block
+		})
+		b.add(1)
+		time.Sleep(10 * time.Millisecond)
+		b.add(2)
+		if b.add(3) {
+			t.Error("Expected item to be dropped")
+		}
+		if atomic.LoadUint64(&b.dropped) != 1 {
+			t.Errorf("Unexpected number of dropped items %d", b.dropped)
+		}
+		close(block)
+		b.close()
+	})
+	t.Run("concurrent close", func(t *testing.T) {
+		var flushed, added uint64
+		b := newBatcher(1000, 10, time.Hour, func(items []interface{}) {
+			atomic.AddUint64(&flushed, uint64(len(items)))
+		})
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					if b.add(j) {
+						atomic.AddUint64(&added, 1)
+					}
+				}
+			}()
+		}
+		b.close()
+		wg.Wait()
+		if flushed != atomic.LoadUint64(&added) {
+			t.Errorf("Expected all %d accepted items to be flushed, got %d", added, flushed)
+		}
+	})
+}
@@ -0,0 +1,164 @@
+/*
+Package botframework converts Microsoft Bot Framework activities into
+Chatbase messages and events.
+
+Incoming message activities are recorded as user messages and activities sent
+by the bot as agent messages. The platform is derived from the activity's
+channel, the id of the conversation is used as session id. Conversation
+updates are recorded as events:
+
+	client := chatbase.New("MY-API-KEY")
+	message, err := botframework.UserMessage(client, activity)
+*/
+package botframework
+
+import (
+	"errors"
+	"time"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+// Activity types handled by this package
+const (
+	MessageActivity            = "message"
+	ConversationUpdateActivity = "conversationUpdate"
+)
+
+// Intents of the events recorded for conversation updates
+const (
+	MemberAddedIntent   = "member_added"
+	MemberRemovedIntent = "member_removed"
+)
+
+// ErrUnsupportedActivity is returned when an activity cannot be
+// converted into the requested type
+var ErrUnsupportedActivity = errors.New("activity type is not supported")
+
+// channels maps Bot Framework channel ids to Chatbase platforms
+var channels = map[string]string{
+	"skype":      chatbase.PlatformSkype,
+	"cortana":    chatbase.PlatformCortana,
+	"facebook":   chatbase.PlatformFacebook,
+	"slack":      chatbase.PlatformSlack,
+	"telegram":   chatbase.PlatformTelegram,
+	"kik":        chatbase.PlatformKik,
+	"line":       chatbase.PlatformLine,
+	"sms":        chatbase.PlatformSMS,
+	"webchat":    chatbase.PlatformWeb,
+	"directline": chatbase.PlatformWeb,
+	"msteams":    "Teams",
+	"email":      "Email",
+	"emulator":   "Emulator",
+}
+
+// Activity is a Bot Framework activity
+type Activity struct {
+	Type           string              `json:"type"`
+	ID             string              `json:"id,omitempty"`
+	Timestamp      string              `json:"timestamp,omitempty"`
+	ServiceURL     string              `json:"serviceUrl,omitempty"`
+	ChannelID      string              `json:"channelId"`
+	From           ChannelAccount      `json:"from"`
+	Conversation   ConversationAccount `json:"conversation"`
+	Recipient      ChannelAccount      `json:"recipient"`
+	Text           string              `json:"text,omitempty"`
+	Speak          string              `json:"speak,omitempty"`
+	ReplyToID      string              `json:"replyToId,omitempty"`
+	MembersAdded   []ChannelAccount    `json:"membersAdded,omitempty"`
+	MembersRemoved []ChannelAccount    `json:"membersRemoved,omitempty"`
+	Value          interface{}         `json:"value,omitempty"`
+}
+
+// ChannelAccount identifies a user or bot on a channel
+type ChannelAccount struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	Role string `json:"role,omitempty"`
+}
+
+// ConversationAccount identifies a conversation
+type ConversationAccount struct {
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	IsGroup bool   `json:"isGroup,omitempty"`
+}
+
+// Platform returns the Chatbase platform of the activity's channel.
+// Unknown channels use the channel id as platform
+func (a *Activity) Platform() string {
+	if platform, ok := channels[a.ChannelID]; ok {
+		return platform
+	}
+	return a.ChannelID
+}
+
+// UserMessage converts an incoming message activity into a user message
+func UserMessage(client *chatbase.Client, a *Activity) (*chatbase.Message, error) {
+	if a.Type != MessageActivity || a.From.ID == "" {
+		return nil, ErrUnsupportedActivity
+	}
+	m := client.UserMessage(a.From.ID, a.Platform()).
+		SetMessage(a.Text).
+		SetSessionID(a.Conversation.ID)
+	if ts, ok := timeStamp(a.Timestamp); ok {
+		m.SetTimeStamp(ts)
+	}
+	return m, nil
+}
+
+// AgentMessage converts an activity sent by the bot into an agent message.
+// The recipient of the activity is used as user id, messages without any
+// text use the activity's speak field
+func AgentMessage(client *chatbase.Client, a *Activity) (*chatbase.Message, error) {
+	if a.Type != MessageActivity || a.Recipient.ID == "" {
+		return nil, ErrUnsupportedActivity
+	}
+	text := a.Text
+	if text == "" {
+		text = a.Speak
+	}
+	m := client.AgentMessage(a.Recipient.ID, a.Platform()).
+		SetMessage(text).
+		SetSessionID(a.Conversation.ID)
+	if ts, ok := timeStamp(a.Timestamp); ok {
+		m.SetTimeStamp(ts)
+	}
+	return m, nil
+}
+
+// ConversationEvents converts a conversationUpdate activity into one event
+// per member that has been added or removed. The bot itself, which is the
+// recipient of the activity, is skipped
+func ConversationEvents(client *chatbase.Client, a *Activity) (chatbase.Events, error) {
+	if a.Type != ConversationUpdateActivity {
+		return nil, ErrUnsupportedActivity
+	}
+	events := chatbase.Events{}
+	ts, hasTimeStamp := timeStamp(a.Timestamp)
+	add := func(members []ChannelAccount, intent string) {
+		for _, member := range members {
+			if member.ID == "" || member.ID == a.Recipient.ID {
+				continue
+			}
+			e := client.Event(member.ID, intent).SetPlatform(a.Platform())
+			e.AddProperty("conversation_id", a.Conversation.ID)
+			if hasTimeStamp {
+				e.SetTimeStamp(ts)
+			}
+			events.Append(e)
+		}
+	}
+	add(a.MembersAdded, MemberAddedIntent)
+	add(a.MembersRemoved, MemberRemovedIntent)
+	return events, nil
+}
+
+// timeStamp converts the activity's ISO 8601 timestamp into UNIX milliseconds
+func timeStamp(value string) (int64, bool) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return 0, false
+	}
+	return t.UnixNano() / 1e6, true
+}
@@ -0,0 +1,137 @@
+package botframework
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+func decode(t *testing.T, input string) *Activity {
+	var a Activity
+	if err := json.Unmarshal([]byte(input), &a); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	return &a
+}
+
+func TestPlatform(t *testing.T) {
+	tests := []struct {
+		channel  string
+		expected string
+	}{
+		{"skype", chatbase.PlatformSkype},
+		{"cortana", chatbase.PlatformCortana},
+		{"webchat", chatbase.PlatformWeb},
+		{"msteams", "Teams"},
+		{"zap", "zap"},
+	}
+	for _, test := range tests {
+		t.Run(test.channel, func(t *testing.T) {
+			a := Activity{ChannelID: test.channel}
+			if p := a.Platform(); p != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, p)
+			}
+		})
+	}
+}
+
+func TestUserMessage(t *testing.T) {
+	client := chatbase.New("key")
+	tests := []struct {
+		name        string
+		input       string
+		expected    *chatbase.Message
+		expectError bool
+	}{
+		{
+			"default",
+			`{"type":"message","timestamp":"2018-02-01T10:00:00.123Z","channelId":"skype","from":{"id":"user-1"},"conversation":{"id":"conv-1"},"recipient":{"id":"bot"},"text":"Hello"}`,
+			&chatbase.Message{APIKey: "key", Type: chatbase.UserType, UserID: "user-1", TimeStamp: 1517479200123, Platform: "Skype", Message: "Hello", SessionID: "conv-1"},
+			false,
+		},
+		{"typing", `{"type":"typing","channelId":"skype","from":{"id":"user-1"}}`, nil, true},
+		{"no sender", `{"type":"message","channelId":"skype","text":"Hello"}`, nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, err := UserMessage(client, decode(t, test.input))
+			if test.expectError != (err != nil) {
+				t.Errorf("Unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(test.expected, m) {
+				t.Errorf("Expected %#v, got %#v", test.expected, m)
+			}
+		})
+	}
+}
+
+func TestAgentMessage(t *testing.T) {
+	client := chatbase.New("key")
+	tests := []struct {
+		name        string
+		input       string
+		expected    *chatbase.Message
+		expectError bool
+	}{
+		{
+			"default",
+			`{"type":"message","timestamp":"2018-02-01T10:00:01Z","channelId":"cortana","from":{"id":"bot"},"conversation":{"id":"conv-1"},"recipient":{"id":"user-1"},"text":"Hi there"}`,
+			&chatbase.Message{APIKey: "key", Type: chatbase.AgentType, UserID: "user-1", TimeStamp: 1517479201000, Platform: "Cortana", Message: "Hi there", SessionID: "conv-1"},
+			false,
+		},
+		{
+			"speak",
+			`{"type":"message","timestamp":"2018-02-01T10:00:01Z","channelId":"cortana","conversation":{"id":"conv-1"},"recipient":{"id":"user-1"},"speak":"Hi there"}`,
+			&chatbase.Message{APIKey: "key", Type: chatbase.AgentType, UserID: "user-1", TimeStamp: 1517479201000, Platform: "Cortana", Message: "Hi there", SessionID: "conv-1"},
+			false,
+		},
+		{"no recipient", `{"type":"message","channelId":"skype","text":"Hello"}`, nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, err := AgentMessage(client, decode(t, test.input))
+			if test.expectError != (err != nil) {
+				t.Errorf("Unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(test.expected, m) {
+				t.Errorf("Expected %#v, got %#v", test.expected, m)
+			}
+		})
+	}
+}
+
+func TestConversationEvents(t *testing.T) {
+	client := chatbase.New("key")
+	property, _ := chatbase.NewEventProperty("conversation_id", "conv-1")
+	tests := []struct {
+		name        string
+		input       string
+		expected    chatbase.Events
+		expectError bool
+	}{
+		{
+			"default",
+			`{"type":"conversationUpdate","timestamp":"2018-02-01T10:00:00Z","channelId":"skype","conversation":{"id":"conv-1"},"recipient":{"id":"bot"},"membersAdded":[{"id":"bot"},{"id":"user-1"}],"membersRemoved":[{"id":"user-2"}]}`,
+			chatbase.Events{
+				{APIKey: "key", UserID: "user-1", Intent: MemberAddedIntent, TimeStamp: 1517479200000, Platform: "Skype", Properties: []chatbase.EventProperty{property}},
+				{APIKey: "key", UserID: "user-2", Intent: MemberRemovedIntent, TimeStamp: 1517479200000, Platform: "Skype", Properties: []chatbase.EventProperty{property}},
+			},
+			false,
+		},
+		{"only bot", `{"type":"conversationUpdate","channelId":"skype","recipient":{"id":"bot"},"membersAdded":[{"id":"bot"}]}`, chatbase.Events{}, false},
+		{"message", `{"type":"message","channelId":"skype"}`, nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events, err := ConversationEvents(client, decode(t, test.input))
+			if test.expectError != (err != nil) {
+				t.Errorf("Unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(test.expected, events) {
+				t.Errorf("Expected %#v, got %#v", test.expected, events)
+			}
+		})
+	}
+}
@@ -1,12 +1,43 @@
 package chatbase
 
+import (
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
 // Client wraps a Chatbase API Key and can be used to
 // generate messages, events and link
 type Client string
 
-// New returns a new Client using the given Chatbase API Key
-func New(apiKey string) *Client {
+// configs holds the configuration of clients that have been created using
+// options. As a Client is a plain string, the configuration is looked up by
+// the address New returned, it is removed once the client is unreachable
+var configs = struct {
+	sync.RWMutex
+	clients map[uintptr]*config
+}{clients: map[uintptr]*config{}}
+
+// New returns a new Client using the given Chatbase API Key. Options
+// configure how payloads created by the client are being submitted. They
+// apply to the returned pointer only, not to copies of the Client
+func New(apiKey string, options ...Option) *Client {
 	c := Client(apiKey)
+	if len(options) == 0 {
+		return &c
+	}
+	cfg := &config{}
+	for _, option := range options {
+		option(cfg)
+	}
+	configs.Lock()
+	configs.clients[uintptr(unsafe.Pointer(&c))] = cfg
+	configs.Unlock()
+	runtime.SetFinalizer(&c, func(c *Client) {
+		configs.Lock()
+		delete(configs.clients, uintptr(unsafe.Pointer(c)))
+		configs.Unlock()
+	})
 	return &c
 }
 
@@ -14,6 +45,14 @@ func (c *Client) String() string {
 	return string(*c)
 }
 
+// config returns the configuration shared with all payloads created by the
+// client. It is nil unless the client has been created using options
+func (c *Client) config() *config {
+	configs.RLock()
+	defer configs.RUnlock()
+	return configs.clients[uintptr(unsafe.Pointer(c))]
+}
+
 // Message returns a new Message using the client's key and
 // the current time as its "TimeStamp" value
 func (c *Client) Message(typ MessageType, userID, platform string) *Message {
@@ -22,7 +61,10 @@ func (c *Client) Message(typ MessageType, userID, platform string) *Message {
 		Type:      typ,
 		UserID:    userID,
 		TimeStamp: TimeStamp(),
-		Platform:  platform,
+		Platform:  c.config().platformOr(platform),
+		Version:   c.config().defaultVersion(),
+		SessionID: c.config().sessionID(userID),
+		config:    c.config(),
 	}
 }
 
@@ -38,10 +80,16 @@ func (c *Client) AgentMessage(userID, platform string) *Message {
 
 // Event creates a new Event using the client's API Key
 func (c *Client) Event(userID, intent string) *Event {
+	properties := c.config().defaultProperties()
 	return &Event{
-		APIKey: c.String(),
-		UserID: userID,
-		Intent: intent,
+		APIKey:     c.String(),
+		UserID:     userID,
+		Intent:     intent,
+		Platform:   c.config().platformOr(""),
+		Version:    c.config().defaultVersion(),
+		Properties: properties,
+		config:     c.config(),
+		defaults:   len(properties),
 	}
 }
 
@@ -50,14 +98,17 @@ func (c *Client) Update(messageID string) *Update {
 	return &Update{
 		APIKey:    c.String(),
 		MessageID: MessageID(messageID),
+		config:    c.config(),
 	}
 }
 
 // FacebookMessage creates a new native Facebook message
 func (c *Client) FacebookMessage(payload interface{}) *FacebookMessage {
 	return &FacebookMessage{
+		Fields:  c.config().facebookFields(),
 		Payload: payload,
 		APIKey:  c.String(),
+		config:  c.config(),
 	}
 }
 
@@ -67,14 +118,30 @@ func (c *Client) FacebookRequestResponse(request, response interface{}) *Faceboo
 		APIKey:   c.String(),
 		Request:  request,
 		Response: response,
+		Fields:   c.config().facebookFields(),
+		config:   c.config(),
 	}
 }
 
+// FacebookMessageFromEvent creates a new native Facebook message
+// using a typed Messenger webhook event
+func (c *Client) FacebookMessageFromEvent(event *MessengerEvent) *FacebookMessage {
+	return c.FacebookMessage(event)
+}
+
+// FacebookRequestResponseFromSend creates a new wrapper around a typed
+// Send API request and its response
+func (c *Client) FacebookRequestResponseFromSend(request *MessengerSendRequest, response *MessengerSendResponse) *FacebookRequestResponse {
+	return c.FacebookRequestResponse(request, response)
+}
+
 // Link returns a trackable link to the given URL
 func (c *Client) Link(url, platform string) *Link {
 	return &Link{
 		APIKey:   c.String(),
 		URL:      url,
-		Platform: platform,
+		Platform: c.config().platformOr(platform),
+		Version:  c.config().defaultVersion(),
+		config:   c.config(),
 	}
 }
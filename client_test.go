@@ -2,7 +2,9 @@ package chatbase
 
 import (
 	"reflect"
+	"runtime"
 	"testing"
+	"time"
 )
 
 func TestNewClient(t *testing.T) {
@@ -11,9 +13,65 @@ func TestNewClient(t *testing.T) {
 		if c.String() != "foo-bar-baz" {
 			t.Errorf("Expected foo-bar-baz, got %v", c.String())
 		}
+		if c.config() != nil {
+			t.Errorf("Expected no config, got %v", c.config())
+		}
+	})
+	t.Run("options", func(t *testing.T) {
+		observer := &recordingObserver{}
+		c := New("foo-bar-baz", WithPipelineObserver(observer))
+		if c.config() == nil || c.config().observer != observer {
+			t.Fatalf("Expected options to be applied, got %v", c.config())
+		}
+		payloads := []*config{
+			c.UserMessage("abc123", "fantasy-chat").config,
+			c.Event("abc123", "intent").config,
+			c.Update("123").config,
+			c.FacebookMessage(nil).config,
+			c.FacebookRequestResponse(nil, nil).config,
+			c.Link("https://example.net", "fantasy-chat").config,
+		}
+		for i, p := range payloads {
+			if p != c.config() {
+				t.Errorf("Expected payload %d to use the client's config, got %v", i, p)
+			}
+		}
 	})
 }
 
+func TestClient_String(t *testing.T) {
+	c := Client("foo-bar-baz")
+	if string(c) != "foo-bar-baz" || c.String() != "foo-bar-baz" || c.config() != nil {
+		t.Errorf("Unexpected client %v", c)
+	}
+	if key := string(*New("foo-bar-baz", WithVersion("1"))); key != "foo-bar-baz" {
+		t.Errorf("Unexpected key %v", key)
+	}
+}
+
+func TestClient_ReleaseConfig(t *testing.T) {
+	count := func() int {
+		configs.RLock()
+		defer configs.RUnlock()
+		return len(configs.clients)
+	}
+	before := count()
+	func() {
+		c := New("foo-bar-baz", WithVersion("1"))
+		if count() != before+1 || c.config() == nil {
+			t.Fatalf("Expected config to be stored")
+		}
+	}()
+	deadline := time.Now().Add(time.Second)
+	for count() > before {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected config of unreachable client to be released")
+		}
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+}
+
 func TestMessage_Client(t *testing.T) {
 	oldTimeStamp := TimeStamp
 	defer func() { TimeStamp = oldTimeStamp }()
@@ -120,3 +178,35 @@ func TestFacebookRequestResponse_Client(t *testing.T) {
 		}
 	})
 }
+
+func TestFacebookMessageFromEvent_Client(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		c := New("foo-bar-baz")
+		event := &MessengerEvent{Sender: MessengerUser{ID: "abc"}}
+		expected := &FacebookMessage{
+			APIKey:  "foo-bar-baz",
+			Payload: event,
+		}
+		f := c.FacebookMessageFromEvent(event)
+		if !reflect.DeepEqual(expected, f) {
+			t.Errorf("Expected %#v, got %#v", expected, f)
+		}
+	})
+}
+
+func TestFacebookRequestResponseFromSend_Client(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		c := New("foo-bar-baz")
+		req := &MessengerSendRequest{Recipient: MessengerUser{ID: "abc"}}
+		res := &MessengerSendResponse{RecipientID: "abc"}
+		expected := &FacebookRequestResponse{
+			APIKey:   "foo-bar-baz",
+			Request:  req,
+			Response: res,
+		}
+		f := c.FacebookRequestResponseFromSend(req, res)
+		if !reflect.DeepEqual(expected, f) {
+			t.Errorf("Expected %#v, got %#v", expected, f)
+		}
+	})
+}
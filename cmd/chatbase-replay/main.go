@@ -0,0 +1,85 @@
+// Command chatbase-replay resubmits archived Chatbase traffic read from
+// newline delimited JSON files against another API key.
+//
+//	chatbase-replay -key STAGING-API-KEY -shift -speed 10 traffic.jsonl
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+	"github.com/m90/go-chatbase/v2/replay"
+)
+
+func main() {
+	var (
+		apiKey     = flag.String("key", os.Getenv("CHATBASE_API_KEY"), "the API key to replay traffic against, keeps the archived keys if empty")
+		userPrefix = flag.String("user-prefix", "", "prefix prepended to all archived user ids")
+		shift      = flag.Bool("shift", false, "shift time stamps relative to the start of the replay")
+		speed      = flag.Float64("speed", 0, "pace submissions relative to the original traffic, 0 disables pacing")
+		keepGoing  = flag.Bool("keep-going", false, "continue replaying when an item cannot be submitted")
+	)
+	flag.Parse()
+
+	if err := run(*apiKey, *userPrefix, *shift, *speed, *keepGoing, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(apiKey, userPrefix string, shift bool, speed float64, keepGoing bool, files []string) error {
+	var items []replay.Item
+	if len(files) == 0 {
+		decoded, err := replay.Decode(os.Stdin)
+		if err != nil {
+			return err
+		}
+		items = decoded
+	}
+	for _, name := range files {
+		decoded, err := decodeFile(name)
+		if err != nil {
+			return err
+		}
+		items = append(items, decoded...)
+	}
+
+	r := &replay.Replayer{
+		ShiftTimeStamps: shift,
+		Speed:           speed,
+	}
+	if apiKey != "" {
+		r.Client = chatbase.New(apiKey)
+	}
+	if userPrefix != "" {
+		r.UserIDs = func(id string) string {
+			return userPrefix + id
+		}
+	}
+	if keepGoing {
+		r.OnError = func(_ replay.Item, err error) {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	return r.Replay(ctx, items)
+}
+
+func decodeFile(name string) ([]replay.Item, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	items, err := replay.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return items, nil
+}
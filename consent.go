@@ -0,0 +1,307 @@
+package chatbase
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Consent is the kind of data that may be submitted for a user
+type Consent int
+
+// Kinds of consent, higher values are more restrictive
+const (
+	// FullConsent allows submitting all data
+	FullConsent Consent = iota
+	// MetadataOnly allows submitting intents, handled flags and other
+	// metadata while message text is stripped
+	MetadataOnly
+	// NoConsent withholds all data
+	NoConsent
+)
+
+// consentPipeline is the pipeline withheld items are reported for
+const consentPipeline = "consent"
+
+// ConsentRegistry decides which data may be submitted for a user. It is
+// consulted before any payload created by a client is submitted. When
+// rules for both the user and the platform exist, the more restrictive
+// one applies
+type ConsentRegistry struct {
+	mu        sync.RWMutex
+	fallback  Consent
+	users     map[string]Consent
+	platforms map[string]Consent
+}
+
+// NewConsentRegistry returns a new ConsentRegistry that uses the given
+// consent for users and platforms without any rule
+func NewConsentRegistry(fallback Consent) *ConsentRegistry {
+	return &ConsentRegistry{
+		fallback:  fallback,
+		users:     map[string]Consent{},
+		platforms: map[string]Consent{},
+	}
+}
+
+// WithConsentRegistry consults the given registry before submitting any
+// payload created by the client. Withheld items are not submitted but
+// reported as successful responses whose Withheld field is set to "consent"
+func WithConsentRegistry(r *ConsentRegistry) Option {
+	return func(c *config) {
+		c.consent = r
+	}
+}
+
+// SetUser sets the consent of the given user
+func (r *ConsentRegistry) SetUser(userID string, consent Consent) *ConsentRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[userID] = consent
+	return r
+}
+
+// OptOut withholds all data of the given users
+func (r *ConsentRegistry) OptOut(userIDs ...string) *ConsentRegistry {
+	for _, userID := range userIDs {
+		r.SetUser(userID, NoConsent)
+	}
+	return r
+}
+
+// SetPlatform sets the consent of all users on the given platform
+func (r *ConsentRegistry) SetPlatform(platform string, consent Consent) *ConsentRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.platforms[platform] = consent
+	return r
+}
+
+// Load sets the given consent for all user ids read from rd. The data is
+// expected to contain one user id per line, empty lines and lines
+// starting with "#" are skipped
+func (r *ConsentRegistry) Load(rd io.Reader, consent Consent) error {
+	var userIDs []string
+	scanner := bufio.NewScanner(rd)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		userIDs = append(userIDs, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, userID := range userIDs {
+		r.users[userID] = consent
+	}
+	return nil
+}
+
+// LoadFile sets the given consent for all user ids listed in the file
+// at the given path, e.g. an opt-out list
+func (r *ConsentRegistry) LoadFile(path string, consent Consent) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return r.Load(f, consent)
+}
+
+// Consent returns the consent of the given user on the given platform
+func (r *ConsentRegistry) Consent(userID, platform string) Consent {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	user, hasUser := r.users[userID]
+	plat, hasPlatform := r.platforms[platform]
+	switch {
+	case hasUser && hasPlatform:
+		if user > plat {
+			return user
+		}
+		return plat
+	case hasUser:
+		return user
+	case hasPlatform:
+		return plat
+	}
+	return r.fallback
+}
+
+// apply returns a copy of the given payload containing only the data that
+// may be submitted, or nil if nothing may be, and the indices of the items
+// that have been withheld
+func (r *ConsentRegistry) apply(v interface{}) (interface{}, []int, error) {
+	switch p := v.(type) {
+	case *Message:
+		messages, withheld := r.messages(Messages{*p})
+		if len(messages) == 0 {
+			return nil, withheld, nil
+		}
+		return &messages[0], withheld, nil
+	case *Messages:
+		messages, withheld := r.messages(*p)
+		if len(messages) == 0 {
+			return nil, withheld, nil
+		}
+		return &messages, withheld, nil
+	case *Event:
+		events, withheld := r.events(Events{*p})
+		if len(events) == 0 {
+			return nil, withheld, nil
+		}
+		return &events[0], withheld, nil
+	case *Events:
+		events, withheld := r.events(*p)
+		if len(events) == 0 {
+			return nil, withheld, nil
+		}
+		return &events, withheld, nil
+	case *FacebookMessage:
+		messages, withheld, err := r.facebookMessages(FacebookMessages{*p})
+		if err != nil || len(messages) == 0 {
+			return nil, withheld, err
+		}
+		return &messages[0], withheld, nil
+	case *FacebookMessages:
+		messages, withheld, err := r.facebookMessages(*p)
+		if err != nil || len(messages) == 0 {
+			return nil, withheld, err
+		}
+		return &messages, withheld, nil
+	case *FacebookRequestResponse:
+		pairs, withheld, err := r.facebookRequestResponses(FacebookRequestResponses{*p})
+		if err != nil || len(pairs) == 0 {
+			return nil, withheld, err
+		}
+		return &pairs[0], withheld, nil
+	case *FacebookRequestResponses:
+		pairs, withheld, err := r.facebookRequestResponses(*p)
+		if err != nil || len(pairs) == 0 {
+			return nil, withheld, err
+		}
+		return &pairs, withheld, nil
+	}
+	return v, nil, nil
+}
+
+func (r *ConsentRegistry) messages(messages Messages) (Messages, []int) {
+	result := Messages{}
+	var withheld []int
+	for i, m := range messages {
+		switch r.Consent(m.UserID, m.Platform) {
+		case NoConsent:
+			withheld = append(withheld, i)
+			continue
+		case MetadataOnly:
+			m.Message = ""
+		}
+		result = append(result, m)
+	}
+	return result, withheld
+}
+
+// events are not stripped in metadata only mode as they do not
+// contain any message text
+func (r *ConsentRegistry) events(events Events) (Events, []int) {
+	result := Events{}
+	var withheld []int
+	for i, e := range events {
+		if r.Consent(e.UserID, e.Platform) == NoConsent {
+			withheld = append(withheld, i)
+			continue
+		}
+		result = append(result, e)
+	}
+	return result, withheld
+}
+
+func (r *ConsentRegistry) facebookMessages(messages FacebookMessages) (FacebookMessages, []int, error) {
+	result := FacebookMessages{}
+	var withheld []int
+	for i, f := range messages {
+		payload, err := genericJSON(f.Payload)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch r.Consent(facebookID(payload, "sender"), PlatformFacebook) {
+		case NoConsent:
+			withheld = append(withheld, i)
+			continue
+		case MetadataOnly:
+			f.Payload = stripText(payload)
+		}
+		result = append(result, f)
+	}
+	return result, withheld, nil
+}
+
+func (r *ConsentRegistry) facebookRequestResponses(pairs FacebookRequestResponses) (FacebookRequestResponses, []int, error) {
+	result := FacebookRequestResponses{}
+	var withheld []int
+	for i, f := range pairs {
+		request, err := genericJSON(f.Request)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch r.Consent(facebookID(request, "recipient"), PlatformFacebook) {
+		case NoConsent:
+			withheld = append(withheld, i)
+			continue
+		case MetadataOnly:
+			f.Request = stripText(request)
+		}
+		result = append(result, f)
+	}
+	return result, withheld, nil
+}
+
+// facebookID returns the id of the first object stored under the given
+// key in a generic Facebook payload
+func facebookID(v interface{}, key string) string {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		if account, ok := value[key].(map[string]interface{}); ok {
+			if id, ok := account["id"].(string); ok {
+				return id
+			}
+		}
+		for _, child := range value {
+			if id := facebookID(child, key); id != "" {
+				return id
+			}
+		}
+	case []interface{}:
+		for _, child := range value {
+			if id := facebookID(child, key); id != "" {
+				return id
+			}
+		}
+	}
+	return ""
+}
+
+// stripText removes all "text" fields of a generic Facebook payload
+func stripText(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if _, ok := child.(string); ok && key == "text" {
+				delete(value, key)
+				continue
+			}
+			stripText(child)
+		}
+	case []interface{}:
+		for _, child := range value {
+			stripText(child)
+		}
+	}
+	return v
+}
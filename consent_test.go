@@ -0,0 +1,225 @@
+package chatbase
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConsentRegistry_Consent(t *testing.T) {
+	r := NewConsentRegistry(FullConsent).
+		SetPlatform(PlatformSMS, MetadataOnly).
+		SetPlatform(PlatformWeb, NoConsent).
+		SetUser("trusted", FullConsent).
+		SetUser("careful", MetadataOnly).
+		OptOut("gone")
+	tests := []struct {
+		userID   string
+		platform string
+		expected Consent
+	}{
+		{"unknown", PlatformFacebook, FullConsent},
+		{"unknown", PlatformSMS, MetadataOnly},
+		{"unknown", PlatformWeb, NoConsent},
+		{"trusted", PlatformSMS, MetadataOnly},
+		{"careful", PlatformFacebook, MetadataOnly},
+		{"careful", PlatformWeb, NoConsent},
+		{"gone", PlatformFacebook, NoConsent},
+		{"gone", PlatformSMS, NoConsent},
+	}
+	for _, test := range tests {
+		t.Run(test.userID+" on "+test.platform, func(t *testing.T) {
+			if result := r.Consent(test.userID, test.platform); result != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+
+	t.Run("fallback", func(t *testing.T) {
+		r := NewConsentRegistry(NoConsent).SetUser("opted-in", FullConsent)
+		if c := r.Consent("someone", PlatformWeb); c != NoConsent {
+			t.Errorf("Expected NoConsent, got %v", c)
+		}
+		if c := r.Consent("opted-in", PlatformWeb); c != FullConsent {
+			t.Errorf("Expected FullConsent, got %v", c)
+		}
+	})
+}
+
+func TestConsentRegistry_LoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chatbase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "optout.txt")
+	ioutil.WriteFile(path, []byte("# opted out via support\nuser-1\n\n  user-2  \n"), 0600)
+
+	r := NewConsentRegistry(FullConsent)
+	if err := r.LoadFile(path, NoConsent); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if err := r.Load(strings.NewReader("user-3"), MetadataOnly); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := map[string]Consent{"user-1": NoConsent, "user-2": NoConsent, "user-3": MetadataOnly}
+	if !reflect.DeepEqual(expected, r.users) {
+		t.Errorf("Expected %v, got %v", expected, r.users)
+	}
+	if err := r.LoadFile(filepath.Join(dir, "missing.txt"), NoConsent); err == nil {
+		t.Error("Expected error for missing file, got nil")
+	}
+}
+
+func TestConsentRegistry_apply(t *testing.T) {
+	r := NewConsentRegistry(FullConsent).OptOut("gone", "999").SetUser("careful", MetadataOnly)
+	tests := []struct {
+		name             string
+		payload          interface{}
+		expected         interface{}
+		expectedWithheld []int
+	}{
+		{
+			"message",
+			&Message{UserID: "user", Message: "hi"},
+			&Message{UserID: "user", Message: "hi"},
+			nil,
+		},
+		{
+			"metadata only message",
+			&Message{UserID: "careful", Message: "hi", Intent: "greet", NotHandled: true},
+			&Message{UserID: "careful", Intent: "greet", NotHandled: true},
+			nil,
+		},
+		{
+			"withheld message",
+			&Message{UserID: "gone", Message: "hi"},
+			nil,
+			[]int{0},
+		},
+		{
+			"messages",
+			&Messages{{UserID: "user", Message: "hi"}, {UserID: "gone"}, {UserID: "careful", Message: "hi"}},
+			&Messages{{UserID: "user", Message: "hi"}, {UserID: "careful"}},
+			[]int{1},
+		},
+		{
+			"events",
+			&Events{{UserID: "gone"}, {UserID: "careful", Intent: "signup"}},
+			&Events{{UserID: "careful", Intent: "signup"}},
+			[]int{0},
+		},
+		{
+			"withheld event",
+			&Event{UserID: "gone"},
+			nil,
+			[]int{0},
+		},
+		{
+			"facebook messages",
+			&FacebookMessages{
+				{Payload: json.RawMessage(`{"sender":{"id":"999"},"message":{"text":"hi"}}`)},
+				{Payload: json.RawMessage(`{"sender":{"id":"careful"},"message":{"text":"hi","mid":"m"}}`)},
+			},
+			&FacebookMessages{
+				{Payload: map[string]interface{}{
+					"sender":  map[string]interface{}{"id": "careful"},
+					"message": map[string]interface{}{"mid": "m"},
+				}},
+			},
+			[]int{0},
+		},
+		{
+			"withheld facebook request response",
+			&FacebookRequestResponse{Request: map[string]interface{}{"recipient": map[string]interface{}{"id": "999"}}},
+			nil,
+			[]int{0},
+		},
+		{
+			"update",
+			&Update{Intent: "greet"},
+			&Update{Intent: "greet"},
+			nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, withheld, err := r.apply(test.payload)
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(test.expectedWithheld, withheld) {
+				t.Errorf("Expected withheld items %v, got %v", test.expectedWithheld, withheld)
+			}
+			if !reflect.DeepEqual(test.expected, result) {
+				t.Errorf("Expected %#v, got %#v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestWithConsentRegistry(t *testing.T) {
+	oldEndpoint := messagesEndpoint
+	defer func() { messagesEndpoint = oldEndpoint }()
+
+	var requests int
+	var received Messages
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var body struct {
+			Messages Messages `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		received = body.Messages
+		w.Write([]byte(`{"all_succeeded":true,"status":200,"responses":[{"message_id":"1","status":"success"}]}`))
+	}))
+	defer ts.Close()
+	messagesEndpoint = ts.URL
+
+	observer := &recordingObserver{}
+	c := New("key", WithPipelineObserver(observer), WithConsentRegistry(NewConsentRegistry(FullConsent).OptOut("gone")))
+
+	messages := Messages{}
+	messages.Append(c.UserMessage("gone", PlatformWeb).SetMessage("bye"))
+	messages.Append(c.UserMessage("user", PlatformWeb).SetMessage("hi"))
+	res, err := messages.Submit()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if requests != 1 || len(received) != 1 || received[0].UserID != "user" {
+		t.Errorf("Unexpected submission %v", received)
+	}
+	expected := []MessageResponse{
+		{Status: true, Reason: "withheld by consent", Withheld: consentPipeline},
+		{MessageID: "1", Status: true},
+	}
+	if !reflect.DeepEqual(expected, res.Responses) {
+		t.Errorf("Expected aligned responses %#v, got %#v", expected, res.Responses)
+	}
+
+	withheld := Messages{}
+	withheld.Append(c.UserMessage("gone", PlatformWeb).SetMessage("bye"))
+	res, err = withheld.Submit()
+	if err != nil || !res.AllSucceeded || len(res.Responses) != 1 || res.Responses[0].Withheld != consentPipeline {
+		t.Errorf("Expected withheld result, got %#v %v", res, err)
+	}
+	single, err := c.UserMessage("gone", PlatformWeb).Submit()
+	if err != nil || !single.Status.OK() || single.Withheld != consentPipeline || single.MessageID != "" {
+		t.Errorf("Expected withheld result, got %#v %v", single, err)
+	}
+	if err := c.Event("gone", "signup").Submit(); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected withheld payloads not to be submitted, got %d requests", requests)
+	}
+	if dropped := observer.dropped(consentPipeline); dropped != 4 {
+		t.Errorf("Expected 4 dropped items, got %v", dropped)
+	}
+}
@@ -0,0 +1,304 @@
+/*
+Package csvimport reads chat transcripts that have been exported as CSV
+and turns them into Chatbase messages, preserving their original timestamps.
+
+	importer := csvimport.New(chatbase.New("MY-API-KEY"), csvimport.Columns{
+		Type:      "direction",
+		UserID:    "customer",
+		TimeStamp: "created_at",
+		Platform:  "channel",
+		Message:   "text",
+	})
+	responses, err := importer.Import(file)
+	if rowErrs, ok := err.(csvimport.RowErrors); ok {
+		for _, rowErr := range rowErrs {
+			fmt.Println(rowErr)
+		}
+	}
+*/
+package csvimport
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+// Special values that can be used in TimeFormats for reading numeric
+// UNIX timestamps instead of formatted dates
+const (
+	UnixSeconds      = "unix"
+	UnixMilliseconds = "unixms"
+)
+
+// DefaultTimeFormats are tried in order when no TimeFormats are configured
+var DefaultTimeFormats = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	UnixMilliseconds,
+}
+
+// DefaultChunkSize is the number of messages submitted in a single request
+// when no ChunkSize is configured
+const DefaultChunkSize = 100
+
+// Columns maps the fields of a Message to the header names used in the
+// CSV file. Type, UserID and TimeStamp are required, Platform is required
+// unless a fallback platform is configured on the Importer. Empty values
+// mean the column is not present.
+type Columns struct {
+	Type       string
+	UserID     string
+	TimeStamp  string
+	Platform   string
+	Message    string
+	Intent     string
+	NotHandled string
+	SessionID  string
+}
+
+// Importer maps the rows of a CSV transcript to messages
+type Importer struct {
+	Client  *chatbase.Client
+	Columns Columns
+	// Platform is used for rows that do not specify a platform
+	Platform string
+	// Types maps the values of the type column to message types. When nil
+	// "user" and "agent" are recognized (case insensitive)
+	Types map[string]chatbase.MessageType
+	// TimeFormats are the layouts tried in order when parsing timestamps
+	TimeFormats []string
+	// Location is used for layouts that do not contain a time zone
+	Location *time.Location
+	// ChunkSize limits the number of messages per submission
+	ChunkSize int
+	// Comma is the field delimiter, defaulting to ','
+	Comma rune
+}
+
+// New returns a new Importer using the given client and column mapping
+func New(client *chatbase.Client, columns Columns) *Importer {
+	return &Importer{
+		Client:  client,
+		Columns: columns,
+	}
+}
+
+// RowError describes a row that could not be mapped to a message
+type RowError struct {
+	// Row is the 1-based line of the file the row starts at
+	Row int
+	Err error
+}
+
+func (r *RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", r.Row, r.Err)
+}
+
+// RowErrors collects all rows that could not be mapped to a message
+type RowErrors []*RowError
+
+func (r RowErrors) Error() string {
+	msgs := make([]string, len(r))
+	for i, err := range r {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Read parses all rows of the given CSV data into messages. Rows that cannot
+// be mapped are skipped and reported in a RowErrors value that is returned
+// alongside all valid messages. Row numbers are the 1-based lines of the
+// file the rows start at, so quoted fields spanning multiple lines are
+// accounted for.
+func (i *Importer) Read(r io.Reader) (chatbase.Messages, error) {
+	reader := csv.NewReader(r)
+	if i.Comma != 0 {
+		reader.Comma = i.Comma
+	}
+	reader.FieldsPerRecord = -1
+
+	header, headerErr := reader.Read()
+	if headerErr != nil {
+		return nil, fmt.Errorf("could not read header: %v", headerErr)
+	}
+	index, indexErr := i.indexColumns(header)
+	if indexErr != nil {
+		return nil, indexErr
+	}
+
+	messages := chatbase.Messages{}
+	var rowErrs RowErrors
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if parseErr, ok := err.(*csv.ParseError); ok {
+				rowErrs = append(rowErrs, &RowError{Row: parseErr.StartLine, Err: err})
+				continue
+			}
+			return nil, err
+		}
+		message, messageErr := i.message(index, record)
+		if messageErr != nil {
+			line, _ := reader.FieldPos(0)
+			rowErrs = append(rowErrs, &RowError{Row: line, Err: messageErr})
+			continue
+		}
+		messages.Append(message)
+	}
+	if len(rowErrs) > 0 {
+		return messages, rowErrs
+	}
+	return messages, nil
+}
+
+// Import reads the given CSV data and submits all valid messages to Chatbase
+// in chunks. Rows that cannot be mapped are reported using RowErrors after all
+// chunks have been submitted, an error submitting a chunk aborts the import.
+func (i *Importer) Import(r io.Reader) ([]*chatbase.MessagesResponse, error) {
+	messages, readErr := i.Read(r)
+	if _, ok := readErr.(RowErrors); readErr != nil && !ok {
+		return nil, readErr
+	}
+
+	size := i.ChunkSize
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	var responses []*chatbase.MessagesResponse
+	for start := 0; start < len(messages); start += size {
+		end := start + size
+		if end > len(messages) {
+			end = len(messages)
+		}
+		chunk := messages[start:end]
+		res, err := chunk.Submit()
+		if err != nil {
+			return responses, fmt.Errorf("submitting rows %d to %d failed: %v", start+1, end, err)
+		}
+		responses = append(responses, res)
+	}
+	return responses, readErr
+}
+
+func (i *Importer) indexColumns(header []string) (map[string]int, error) {
+	index := map[string]int{}
+	for pos, name := range header {
+		index[strings.TrimSpace(name)] = pos
+	}
+	required := map[string]string{
+		"type":       i.Columns.Type,
+		"user id":    i.Columns.UserID,
+		"time stamp": i.Columns.TimeStamp,
+	}
+	if i.Platform == "" {
+		required["platform"] = i.Columns.Platform
+	}
+	for field, column := range required {
+		if column == "" {
+			return nil, fmt.Errorf("no column configured for required field %s", field)
+		}
+		if _, ok := index[column]; !ok {
+			return nil, fmt.Errorf("column %q for field %s not found in header", column, field)
+		}
+	}
+	return index, nil
+}
+
+func (i *Importer) message(index map[string]int, record []string) (*chatbase.Message, error) {
+	value := func(column string) string {
+		pos, ok := index[column]
+		if column == "" || !ok || pos >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[pos])
+	}
+
+	typ, typErr := i.messageType(value(i.Columns.Type))
+	if typErr != nil {
+		return nil, typErr
+	}
+	userID := value(i.Columns.UserID)
+	if userID == "" {
+		return nil, errors.New("missing user id")
+	}
+	ts, tsErr := i.timeStamp(value(i.Columns.TimeStamp))
+	if tsErr != nil {
+		return nil, tsErr
+	}
+	platform := value(i.Columns.Platform)
+	if platform == "" {
+		platform = i.Platform
+	}
+	if platform == "" {
+		return nil, errors.New("missing platform")
+	}
+
+	message := i.Client.Message(typ, userID, platform).SetTimeStamp(ts)
+	message.SetMessage(value(i.Columns.Message)).
+		SetIntent(value(i.Columns.Intent)).
+		SetSessionID(value(i.Columns.SessionID))
+	if raw := value(i.Columns.NotHandled); raw != "" {
+		notHandled, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("could not use %q as not handled value", raw)
+		}
+		message.SetNotHandled(notHandled)
+	}
+	return message, nil
+}
+
+func (i *Importer) messageType(raw string) (chatbase.MessageType, error) {
+	if i.Types != nil {
+		if typ, ok := i.Types[raw]; ok {
+			return typ, nil
+		}
+		return "", fmt.Errorf("unknown message type %q", raw)
+	}
+	switch typ := chatbase.MessageType(strings.ToLower(raw)); typ {
+	case chatbase.UserType, chatbase.AgentType:
+		return typ, nil
+	}
+	return "", fmt.Errorf("unknown message type %q", raw)
+}
+
+func (i *Importer) timeStamp(raw string) (int64, error) {
+	if raw == "" {
+		return 0, errors.New("missing time stamp")
+	}
+	formats := i.TimeFormats
+	if len(formats) == 0 {
+		formats = DefaultTimeFormats
+	}
+	location := i.Location
+	if location == nil {
+		location = time.UTC
+	}
+	for _, format := range formats {
+		switch format {
+		case UnixSeconds:
+			if s, err := strconv.ParseInt(raw, 10, 64); err == nil {
+				return s * 1000, nil
+			}
+		case UnixMilliseconds:
+			if ms, err := strconv.ParseInt(raw, 10, 64); err == nil {
+				return ms, nil
+			}
+		default:
+			if t, err := time.ParseInLocation(format, raw, location); err == nil {
+				return t.UnixNano() / 1e6, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("could not parse time stamp %q", raw)
+}
@@ -0,0 +1,230 @@
+package csvimport
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (r roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r(req)
+}
+
+var columns = Columns{
+	Type:       "direction",
+	UserID:     "customer",
+	TimeStamp:  "created_at",
+	Platform:   "channel",
+	Message:    "text",
+	Intent:     "intent",
+	NotHandled: "fallback",
+	SessionID:  "conversation",
+}
+
+func TestImporter_Read(t *testing.T) {
+	tests := []struct {
+		name           string
+		importer       *Importer
+		input          string
+		expectError    bool
+		expectedRows   []int
+		expectedResult chatbase.Messages
+	}{
+		{
+			"default",
+			New(chatbase.New("key"), columns),
+			"direction,customer,created_at,channel,text,intent,fallback,conversation\n" +
+				"user,abc,2018-01-02T15:04:05Z,Web,hi there,greeting,false,s-1\n" +
+				"Agent,abc,1514905446000,Web,hello!,,,s-1\n",
+			false,
+			nil,
+			chatbase.Messages{
+				{APIKey: "key", Type: chatbase.UserType, UserID: "abc", TimeStamp: 1514905445000, Platform: "Web", Message: "hi there", Intent: "greeting", SessionID: "s-1"},
+				{APIKey: "key", Type: chatbase.AgentType, UserID: "abc", TimeStamp: 1514905446000, Platform: "Web", Message: "hello!", SessionID: "s-1"},
+			},
+		},
+		{
+			"bad rows",
+			New(chatbase.New("key"), columns),
+			"direction,customer,created_at,channel,text,intent,fallback,conversation\n" +
+				"user,abc,yesterday,Web,hi there,greeting,false,s-1\n" +
+				"bot,abc,1514905446000,Web,hello!,,,s-1\n" +
+				"user,,1514905446000,Web,hello!,,,s-1\n" +
+				"user,abc,1514905447000,Web,help,,maybe,s-1\n" +
+				"user,abc,1514905448000,Web,bye,,1,s-1\n",
+			true,
+			[]int{2, 3, 4, 5},
+			chatbase.Messages{
+				{APIKey: "key", Type: chatbase.UserType, UserID: "abc", TimeStamp: 1514905448000, Platform: "Web", Message: "bye", NotHandled: true, SessionID: "s-1"},
+			},
+		},
+		{
+			"multiline fields",
+			New(chatbase.New("key"), columns),
+			"direction,customer,created_at,channel,text,intent,fallback,conversation\n" +
+				"user,abc,1514905445000,Web,\"hi\nthere\",,,s-1\n" +
+				"bot,abc,1514905446000,Web,\"hello\n\nhow can I help?\",,,s-1\n" +
+				"user,abc,\"1514905447000\",Web,\"bye\",,,\"s-1\n",
+			true,
+			[]int{4, 7},
+			chatbase.Messages{
+				{APIKey: "key", Type: chatbase.UserType, UserID: "abc", TimeStamp: 1514905445000, Platform: "Web", Message: "hi\nthere", SessionID: "s-1"},
+			},
+		},
+		{
+			"custom types and formats",
+			&Importer{
+				Client: chatbase.New("key"),
+				Columns: Columns{
+					Type:      "from",
+					UserID:    "user",
+					TimeStamp: "date",
+				},
+				Platform:    chatbase.PlatformSMS,
+				Types:       map[string]chatbase.MessageType{"in": chatbase.UserType, "out": chatbase.AgentType},
+				TimeFormats: []string{"02.01.2006 15:04", UnixSeconds},
+				Comma:       ';',
+			},
+			"from;user;date\n" +
+				"in;abc;02.01.2018 15:04\n" +
+				"out;abc;1514905500\n",
+			false,
+			nil,
+			chatbase.Messages{
+				{APIKey: "key", Type: chatbase.UserType, UserID: "abc", TimeStamp: 1514905440000, Platform: "SMS"},
+				{APIKey: "key", Type: chatbase.AgentType, UserID: "abc", TimeStamp: 1514905500000, Platform: "SMS"},
+			},
+		},
+		{
+			"missing column",
+			New(chatbase.New("key"), columns),
+			"direction,customer,channel\nuser,abc,Web\n",
+			true,
+			nil,
+			nil,
+		},
+		{
+			"unconfigured column",
+			New(chatbase.New("key"), Columns{UserID: "customer"}),
+			"direction,customer,channel\nuser,abc,Web\n",
+			true,
+			nil,
+			nil,
+		},
+		{
+			"empty input",
+			New(chatbase.New("key"), columns),
+			"",
+			true,
+			nil,
+			nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := test.importer.Read(strings.NewReader(test.input))
+			if test.expectError != (err != nil) {
+				t.Errorf("Unexpected error %v", err)
+			}
+			if test.expectedRows != nil {
+				rowErrs, ok := err.(RowErrors)
+				if !ok {
+					t.Fatalf("Expected RowErrors, got %v", err)
+				}
+				var rows []int
+				for _, rowErr := range rowErrs {
+					rows = append(rows, rowErr.Row)
+				}
+				if !reflect.DeepEqual(test.expectedRows, rows) {
+					t.Errorf("Expected rows %v, got %v", test.expectedRows, rows)
+				}
+			}
+			if !reflect.DeepEqual(test.expectedResult, result) {
+				t.Errorf("Expected %#v, got %#v", test.expectedResult, result)
+			}
+		})
+	}
+}
+
+func TestImporter_Import(t *testing.T) {
+	defer chatbase.SetAPITransport(nil)
+	var batches []int
+	chatbase.SetAPITransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var payload struct {
+			Messages []chatbase.Message `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		batches = append(batches, len(payload.Messages))
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"all_succeeded":true,"status":200}`)),
+		}, nil
+	}))
+
+	input := "direction,customer,created_at,channel\n"
+	for i := 0; i < 5; i++ {
+		input += "user,abc," + time.Unix(int64(i), 0).UTC().Format(time.RFC3339) + ",Web\n"
+	}
+	input += "user,abc,never,Web\n"
+
+	importer := New(chatbase.New("key"), columns)
+	importer.ChunkSize = 2
+	responses, err := importer.Import(strings.NewReader(input))
+	if rowErrs, ok := err.(RowErrors); !ok || len(rowErrs) != 1 || rowErrs[0].Row != 7 {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if len(responses) != 3 {
+		t.Errorf("Unexpected responses %v", responses)
+	}
+	if expected := []int{2, 2, 1}; !reflect.DeepEqual(expected, batches) {
+		t.Errorf("Expected batches %v, got %v", expected, batches)
+	}
+}
+
+func TestImporter_Import_Withheld(t *testing.T) {
+	defer chatbase.SetAPITransport(nil)
+	requests := 0
+	chatbase.SetAPITransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		requests++
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"all_succeeded":true,"status":200,"responses":[{"message_id":"1","status":200}]}`)),
+		}, nil
+	}))
+
+	input := "direction,customer,created_at,channel\n" +
+		"user,gone,2018-01-02T00:00:00Z,Web\n" +
+		"user,gone,2018-01-02T00:00:01Z,Web\n" +
+		"user,gone,2018-01-02T00:00:02Z,Web\n" +
+		"user,abc,2018-01-02T00:00:03Z,Web\n"
+
+	consent := chatbase.NewConsentRegistry(chatbase.FullConsent).OptOut("gone")
+	importer := New(chatbase.New("key", chatbase.WithConsentRegistry(consent)), columns)
+	importer.ChunkSize = 2
+	responses, err := importer.Import(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(responses) != 2 || requests != 1 {
+		t.Fatalf("Unexpected responses %v after %d requests", responses, requests)
+	}
+	var withheld []string
+	for _, res := range responses {
+		for _, item := range res.Responses {
+			withheld = append(withheld, item.Withheld)
+		}
+	}
+	if expected := []string{"consent", "consent", "consent", ""}; !reflect.DeepEqual(expected, withheld) {
+		t.Errorf("Expected %v, got %v", expected, withheld)
+	}
+}
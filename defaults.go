@@ -0,0 +1,77 @@
+package chatbase
+
+// SessionResolver returns the session id for a message of the given
+// user. An empty string leaves the session id unset
+type SessionResolver func(userID string) string
+
+// WithVersion sets the version of all messages, events, links and Facebook
+// payloads created by the client. Calling SetVersion overrides it
+func WithVersion(v string) Option {
+	return func(c *config) {
+		c.version = v
+	}
+}
+
+// WithPlatform sets the platform of all messages, events and links created
+// by the client without passing a platform. Calling SetPlatform overrides it
+func WithPlatform(p string) Option {
+	return func(c *config) {
+		c.platform = p
+	}
+}
+
+// WithSessionResolver sets the session id of all messages created by the
+// client using the given resolver. Calling SetSessionID overrides it
+func WithSessionResolver(r SessionResolver) Option {
+	return func(c *config) {
+		c.sessionResolver = r
+	}
+}
+
+// WithEventProperties adds the given properties to all events created by
+// the client. Calling AddProperty with the same name overrides it
+func WithEventProperties(properties ...EventProperty) Option {
+	return func(c *config) {
+		c.eventProperties = append(c.eventProperties, properties...)
+	}
+}
+
+// platformOr returns the given platform or the default platform if empty
+func (c *config) platformOr(platform string) string {
+	if c == nil || platform != "" {
+		return platform
+	}
+	return c.platform
+}
+
+func (c *config) defaultVersion() string {
+	if c == nil {
+		return ""
+	}
+	return c.version
+}
+
+func (c *config) sessionID(userID string) string {
+	if c == nil || c.sessionResolver == nil {
+		return ""
+	}
+	return c.sessionResolver(userID)
+}
+
+// defaultProperties returns a copy of the default event properties so
+// events do not share the underlying array
+func (c *config) defaultProperties() []EventProperty {
+	if c == nil || len(c.eventProperties) == 0 {
+		return nil
+	}
+	return append([]EventProperty{}, c.eventProperties...)
+}
+
+// facebookFields returns the fields of a new Facebook payload, which are
+// nil unless a default version is set
+func (c *config) facebookFields() *FacebookFields {
+	if c == nil || c.version == "" {
+		return nil
+	}
+	return &FacebookFields{Version: c.version}
+}
@@ -0,0 +1,73 @@
+package chatbase
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClientDefaults(t *testing.T) {
+	plan, _ := NewEventProperty("plan", "pro")
+	c := New("key",
+		WithVersion("1.2.0"),
+		WithPlatform(PlatformWeb),
+		WithSessionResolver(func(userID string) string { return "session-" + userID }),
+		WithEventProperties(plan),
+	)
+
+	t.Run("message", func(t *testing.T) {
+		m := c.UserMessage("user", "")
+		if m.Version != "1.2.0" || m.Platform != PlatformWeb || m.SessionID != "session-user" {
+			t.Errorf("Unexpected defaults %#v", m)
+		}
+		m = c.AgentMessage("user", PlatformSMS).SetVersion("2.0.0").SetSessionID("other")
+		if m.Version != "2.0.0" || m.Platform != PlatformSMS || m.SessionID != "other" {
+			t.Errorf("Unexpected overrides %#v", m)
+		}
+	})
+
+	t.Run("event", func(t *testing.T) {
+		e := c.Event("user", "signup")
+		expected := []EventProperty{{Name: "plan", StringValue: "pro"}}
+		if e.Version != "1.2.0" || e.Platform != PlatformWeb || !reflect.DeepEqual(expected, e.Properties) {
+			t.Errorf("Unexpected defaults %#v", e)
+		}
+		e.AddProperty("plan", "free")
+		e.AddProperty("seats", 3)
+		e.AddProperty("plan", "team")
+		expected = []EventProperty{{Name: "plan", StringValue: "free"}, {Name: "seats", IntegerValue: 3}, {Name: "plan", StringValue: "team"}}
+		if !reflect.DeepEqual(expected, e.Properties) {
+			t.Errorf("Expected %v, got %v", expected, e.Properties)
+		}
+		if other := c.Event("user", "signup"); other.Properties[0].StringValue != "pro" {
+			t.Errorf("Expected default properties not to be shared, got %v", other.Properties)
+		}
+	})
+
+	t.Run("facebook", func(t *testing.T) {
+		if f := c.FacebookMessage(nil); f.Fields == nil || f.Fields.Version != "1.2.0" {
+			t.Errorf("Unexpected fields %#v", f.Fields)
+		}
+		f := c.FacebookRequestResponse(nil, nil).SetVersion("2.0.0")
+		if f.Fields.Version != "2.0.0" || c.FacebookRequestResponse(nil, nil).Fields.Version != "1.2.0" {
+			t.Errorf("Unexpected fields %#v", f.Fields)
+		}
+	})
+
+	t.Run("link", func(t *testing.T) {
+		if l := c.Link("https://example.com", ""); l.Platform != PlatformWeb || l.Version != "1.2.0" {
+			t.Errorf("Unexpected defaults %#v", l)
+		}
+		if l := c.Link("https://example.com", PlatformSMS).SetVersion("2.0.0"); l.Platform != PlatformSMS || l.Version != "2.0.0" {
+			t.Errorf("Unexpected overrides %#v", l)
+		}
+	})
+
+	t.Run("no defaults", func(t *testing.T) {
+		plain := New("key", WithPlatform(PlatformWeb))
+		m := plain.UserMessage("user", "")
+		e := plain.Event("user", "signup")
+		if m.Version != "" || m.SessionID != "" || e.Properties != nil || plain.FacebookMessage(nil).Fields != nil {
+			t.Errorf("Unexpected defaults %#v %#v", m, e)
+		}
+	})
+}
@@ -0,0 +1,152 @@
+/*
+Package dialogflow records Dialogflow v2 fulfillment webhook calls in Chatbase.
+
+The query sent by the user is recorded as a user message using the intent
+matched by Dialogflow, queries matching a fallback intent are marked as not
+handled. The fulfillment response is recorded as the agent's reply:
+
+	client := chatbase.New("MY-API-KEY")
+	http.Handle("/fulfillment", dialogflow.NewHandler(client, func(r *http.Request, req *dialogflow.WebhookRequest) (*dialogflow.WebhookResponse, error) {
+		return &dialogflow.WebhookResponse{FulfillmentText: "Hello!"}, nil
+	}))
+*/
+package dialogflow
+
+import (
+	"strings"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+// platforms maps the integration names used by Dialogflow in
+// originalDetectIntentRequest.source to Chatbase platforms
+var platforms = map[string]string{
+	"facebook":   chatbase.PlatformFacebook,
+	"slack":      chatbase.PlatformSlack,
+	"telegram":   chatbase.PlatformTelegram,
+	"line":       chatbase.PlatformLine,
+	"skype":      chatbase.PlatformSkype,
+	"kik":        chatbase.PlatformKik,
+	"viber":      chatbase.PlatformViber,
+	"twilio":     chatbase.PlatformSMS,
+	"google":     chatbase.PlatformActions,
+	"twitter":    chatbase.PlatformTwitter,
+	"spark":      "Spark",
+	"hangouts":   "Hangouts",
+	"web":        chatbase.PlatformWeb,
+	"web-widget": chatbase.PlatformWeb,
+}
+
+// WebhookRequest is the request sent by Dialogflow to a fulfillment webhook
+type WebhookRequest struct {
+	ResponseID                  string                       `json:"responseId"`
+	Session                     string                       `json:"session"`
+	QueryResult                 QueryResult                  `json:"queryResult"`
+	OriginalDetectIntentRequest *OriginalDetectIntentRequest `json:"originalDetectIntentRequest,omitempty"`
+}
+
+// QueryResult contains the result of matching the user's query
+type QueryResult struct {
+	QueryText                 string                 `json:"queryText"`
+	LanguageCode              string                 `json:"languageCode,omitempty"`
+	Parameters                map[string]interface{} `json:"parameters,omitempty"`
+	AllRequiredParamsPresent  bool                   `json:"allRequiredParamsPresent,omitempty"`
+	FulfillmentText           string                 `json:"fulfillmentText,omitempty"`
+	FulfillmentMessages       []Message              `json:"fulfillmentMessages,omitempty"`
+	Intent                    Intent                 `json:"intent"`
+	IntentDetectionConfidence float64                `json:"intentDetectionConfidence,omitempty"`
+}
+
+// Intent is the intent matched by Dialogflow
+type Intent struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+	IsFallback  bool   `json:"isFallback,omitempty"`
+}
+
+// OriginalDetectIntentRequest contains the payload sent by the integration
+// that received the user's query
+type OriginalDetectIntentRequest struct {
+	Source  string                 `json:"source"`
+	Version string                 `json:"version,omitempty"`
+	Payload map[string]interface{} `json:"payload,omitempty"`
+}
+
+// Message is a rich response message. Only text messages are recorded
+type Message struct {
+	Platform string       `json:"platform,omitempty"`
+	Text     *TextMessage `json:"text,omitempty"`
+}
+
+// TextMessage contains the text of a rich response message
+type TextMessage struct {
+	Text []string `json:"text"`
+}
+
+// WebhookResponse is the response returned by a fulfillment webhook
+type WebhookResponse struct {
+	FulfillmentText     string                 `json:"fulfillmentText,omitempty"`
+	FulfillmentMessages []Message              `json:"fulfillmentMessages,omitempty"`
+	Source              string                 `json:"source,omitempty"`
+	Payload             map[string]interface{} `json:"payload,omitempty"`
+}
+
+// SessionID returns the id of the Dialogflow session, which is
+// the last segment of the session's resource name
+func (w *WebhookRequest) SessionID() string {
+	return w.Session[strings.LastIndex(w.Session, "/")+1:]
+}
+
+// Platform returns the Chatbase platform of the integration the query was
+// sent through, falling back to the given value for unknown integrations
+func (w *WebhookRequest) Platform(fallback string) string {
+	if w.OriginalDetectIntentRequest == nil {
+		return fallback
+	}
+	if platform, ok := platforms[strings.ToLower(w.OriginalDetectIntentRequest.Source)]; ok {
+		return platform
+	}
+	return fallback
+}
+
+// UserMessage converts the webhook request into a user message. The session
+// id is used as user id as Dialogflow does not identify users across sessions
+func UserMessage(client *chatbase.Client, req *WebhookRequest, platform string) *chatbase.Message {
+	session := req.SessionID()
+	return client.UserMessage(session, req.Platform(platform)).
+		SetMessage(req.QueryResult.QueryText).
+		SetIntent(req.QueryResult.Intent.DisplayName).
+		SetNotHandled(req.QueryResult.Intent.IsFallback).
+		SetSessionID(session)
+}
+
+// AgentMessage converts the fulfillment response into an agent message. If the
+// response does not contain any text, the default response defined in
+// Dialogflow is used
+func AgentMessage(client *chatbase.Client, req *WebhookRequest, res *WebhookResponse, platform string) *chatbase.Message {
+	text := ""
+	if res != nil {
+		text = responseText(res.FulfillmentText, res.FulfillmentMessages)
+	}
+	if text == "" {
+		text = responseText(req.QueryResult.FulfillmentText, req.QueryResult.FulfillmentMessages)
+	}
+	session := req.SessionID()
+	return client.AgentMessage(session, req.Platform(platform)).
+		SetMessage(text).
+		SetIntent(req.QueryResult.Intent.DisplayName).
+		SetSessionID(session)
+}
+
+func responseText(text string, messages []Message) string {
+	if text != "" {
+		return text
+	}
+	var parts []string
+	for _, m := range messages {
+		if m.Text != nil {
+			parts = append(parts, m.Text.Text...)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
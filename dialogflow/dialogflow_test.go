@@ -0,0 +1,80 @@
+package dialogflow
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+const fixture = `{
+	"responseId": "r-1",
+	"session": "projects/bot/agent/sessions/s-123",
+	"queryResult": {
+		"queryText": "what is the weather",
+		"fulfillmentText": "Sorry, I did not get that",
+		"intent": {"name": "projects/bot/agent/intents/1", "displayName": "Default Fallback Intent", "isFallback": true}
+	},
+	"originalDetectIntentRequest": {"source": "telegram"}
+}`
+
+func TestWebhookRequest(t *testing.T) {
+	var req WebhookRequest
+	if err := json.Unmarshal([]byte(fixture), &req); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if id := req.SessionID(); id != "s-123" {
+		t.Errorf("Unexpected session id %v", id)
+	}
+	if p := req.Platform("fallback"); p != chatbase.PlatformTelegram {
+		t.Errorf("Unexpected platform %v", p)
+	}
+	req.OriginalDetectIntentRequest.Source = "unknown"
+	if p := req.Platform("fallback"); p != "fallback" {
+		t.Errorf("Unexpected platform %v", p)
+	}
+	req.OriginalDetectIntentRequest = nil
+	if p := req.Platform("fallback"); p != "fallback" {
+		t.Errorf("Unexpected platform %v", p)
+	}
+}
+
+func TestMessages(t *testing.T) {
+	oldTimeStamp := chatbase.TimeStamp
+	defer func() { chatbase.TimeStamp = oldTimeStamp }()
+	chatbase.TimeStamp = func() int64 { return 998877 }
+	client := chatbase.New("key")
+
+	var req WebhookRequest
+	if err := json.Unmarshal([]byte(fixture), &req); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	t.Run("user message", func(t *testing.T) {
+		expected := &chatbase.Message{APIKey: "key", Type: chatbase.UserType, UserID: "s-123", TimeStamp: 998877, Platform: "Telegram", Message: "what is the weather", Intent: "Default Fallback Intent", NotHandled: true, SessionID: "s-123"}
+		if m := UserMessage(client, &req, "Web"); !reflect.DeepEqual(expected, m) {
+			t.Errorf("Expected %#v, got %#v", expected, m)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		response *WebhookResponse
+		expected string
+	}{
+		{"fulfillment text", &WebhookResponse{FulfillmentText: "It's sunny"}, "It's sunny"},
+		{"fulfillment messages", &WebhookResponse{FulfillmentMessages: []Message{{Text: &TextMessage{Text: []string{"It's", "sunny"}}}}}, "It's\nsunny"},
+		{"default response", &WebhookResponse{}, "Sorry, I did not get that"},
+		{"nil response", nil, "Sorry, I did not get that"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := AgentMessage(client, &req, test.response, "Web")
+			expected := &chatbase.Message{APIKey: "key", Type: chatbase.AgentType, UserID: "s-123", TimeStamp: 998877, Platform: "Telegram", Message: test.expected, Intent: "Default Fallback Intent", SessionID: "s-123"}
+			if !reflect.DeepEqual(expected, m) {
+				t.Errorf("Expected %#v, got %#v", expected, m)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package dialogflow
+
+import (
+	"encoding/json"
+	"net/http"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+// Fulfillment computes the response to a webhook request
+type Fulfillment func(r *http.Request, req *WebhookRequest) (*WebhookResponse, error)
+
+// Handler serves a Dialogflow fulfillment webhook using the given
+// Fulfillment and submits the user's query and the response to Chatbase
+type Handler struct {
+	Client      *chatbase.Client
+	Fulfillment Fulfillment
+	// Platform is used for queries from integrations that cannot be mapped
+	// to a Chatbase platform. Defaults to chatbase.PlatformWeb
+	Platform string
+	// ErrorHandler is called when a fulfillment fails or the messages
+	// cannot be submitted. Errors are discarded when it is nil
+	ErrorHandler func(error)
+}
+
+// NewHandler returns a new Handler using the given client and fulfillment
+func NewHandler(client *chatbase.Client, fulfill Fulfillment) *Handler {
+	return &Handler{
+		Client:      client,
+		Fulfillment: fulfill,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	platform := h.Platform
+	if platform == "" {
+		platform = chatbase.PlatformWeb
+	}
+	messages := chatbase.Messages{}
+	messages.Append(UserMessage(h.Client, &req, platform))
+
+	res, err := h.Fulfillment(r, &req)
+	if err != nil {
+		h.handleError(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		if res == nil {
+			res = &WebhookResponse{}
+		}
+		messages.Append(AgentMessage(h.Client, &req, res, platform))
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			h.handleError(err)
+		}
+	}
+	chatbase.SubmitAsync(h.ErrorHandler, &messages)
+}
+
+func (h *Handler) handleError(err error) {
+	if err != nil && h.ErrorHandler != nil {
+		h.ErrorHandler(err)
+	}
+}
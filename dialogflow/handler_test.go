@@ -0,0 +1,101 @@
+package dialogflow
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (r roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r(req)
+}
+
+func TestHandler(t *testing.T) {
+	defer chatbase.SetAPITransport(nil)
+	submitted := make(chan chatbase.Messages, 1)
+	chatbase.SetAPITransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var payload struct {
+			Messages chatbase.Messages `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		submitted <- payload.Messages
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"all_succeeded":true,"status":200}`)),
+		}, nil
+	}))
+
+	tests := []struct {
+		name             string
+		body             string
+		fulfill          Fulfillment
+		expectedCode     int
+		expectedMessages []string
+	}{
+		{
+			"default",
+			fixture,
+			func(r *http.Request, req *WebhookRequest) (*WebhookResponse, error) {
+				return &WebhookResponse{FulfillmentText: "Try again?"}, nil
+			},
+			http.StatusOK,
+			[]string{"user:what is the weather", "agent:Try again?"},
+		},
+		{
+			"fulfillment error",
+			fixture,
+			func(r *http.Request, req *WebhookRequest) (*WebhookResponse, error) {
+				return nil, errors.New("zalgo")
+			},
+			http.StatusInternalServerError,
+			[]string{"user:what is the weather"},
+		},
+		{
+			"bad request",
+			"{{",
+			nil,
+			http.StatusBadRequest,
+			nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := NewHandler(chatbase.New("key"), test.fulfill)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(test.body)))
+			if rec.Code != test.expectedCode {
+				t.Errorf("Expected status %v, got %v", test.expectedCode, rec.Code)
+			}
+			if test.expectedCode == http.StatusOK {
+				var res WebhookResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || res.FulfillmentText != "Try again?" {
+					t.Errorf("Unexpected response %v", rec.Body.String())
+				}
+			}
+			if test.expectedMessages == nil {
+				return
+			}
+			select {
+			case messages := <-submitted:
+				var result []string
+				for _, m := range messages {
+					result = append(result, string(m.Type)+":"+m.Message)
+				}
+				if strings.Join(result, ",") != strings.Join(test.expectedMessages, ",") {
+					t.Errorf("Expected %v, got %v", test.expectedMessages, result)
+				}
+			case <-time.After(time.Second):
+				t.Error("Expected messages to be submitted")
+			}
+		})
+	}
+}
@@ -20,6 +20,10 @@ type Event struct {
 	Platform   string          `json:"platform,omitempty"`
 	Version    string          `json:"version,omitempty"`
 	Properties []EventProperty `json:"properties"`
+
+	config *config
+	// defaults is the number of leading properties inherited from the client
+	defaults int
 }
 
 // SetTimeStamp adds an optional "timestamp" value to the event
@@ -41,26 +45,43 @@ func (e *Event) SetVersion(v string) *Event {
 }
 
 // AddProperty adds a new property to the event using the given name and value.
-// The passed value needs to be one of `int`, `string`, `bool` or `float64`
+// The passed value needs to be one of `int`, `string`, `bool` or `float64`.
+// A default property of the same name set using WithEventProperties is replaced
 func (e *Event) AddProperty(name string, v interface{}) error {
 	prop, err := NewEventProperty(name, v)
 	if err != nil {
 		return err
 	}
+	for i := 0; i < e.defaults && i < len(e.Properties); i++ {
+		if e.Properties[i].Name == name {
+			e.defaults--
+			e.Properties[i], e.Properties[e.defaults] = e.Properties[e.defaults], prop
+			return nil
+		}
+	}
 	e.Properties = append(e.Properties, prop)
 	return nil
 }
 
 // Submit tries to deliver the event to Chatbase
 func (e *Event) Submit() error {
-	_, err := apiPost(eventEndpoint, e)
-	return err
+	return e.submit(context.Background())
 }
 
 // SubmitWithContext tries to deliver the event to Chatbase
 // while considering the given context's deadline
 func (e *Event) SubmitWithContext(ctx context.Context) error {
-	return withContext(ctx, e.Submit)
+	return withContext(ctx, func() error {
+		return e.submit(ctx)
+	})
+}
+
+func (e *Event) submit(ctx context.Context) error {
+	body, err := apiPost(ctx, e.config, eventEndpoint, e)
+	if body != nil {
+		body.Close()
+	}
+	return err
 }
 
 // Events is a collection of Event
@@ -81,14 +102,34 @@ func (e Events) MarshalJSON() ([]byte, error) {
 
 // Submit tries to deliver the set of events to Chatbase
 func (e *Events) Submit() error {
-	_, err := apiPost(eventsEndpoint, e)
-	return err
+	return e.submit(context.Background())
 }
 
 // SubmitWithContext tries to deliver the set of events to Chatbase
 // while considering the context's deadline
 func (e *Events) SubmitWithContext(ctx context.Context) error {
-	return withContext(ctx, e.Submit)
+	return withContext(ctx, func() error {
+		return e.submit(ctx)
+	})
+}
+
+func (e *Events) submit(ctx context.Context) error {
+	cfg, err := e.config()
+	if err != nil {
+		return err
+	}
+	body, err := apiPost(ctx, cfg, eventsEndpoint, e)
+	if body != nil {
+		body.Close()
+	}
+	return err
+}
+
+// config returns the configuration shared by the collection's events
+func (e *Events) config() (*config, error) {
+	return collectionConfig(len(*e), func(i int) *config {
+		return (*e)[i].config
+	})
 }
 
 // Append adds events to the the collection. The collection should not
@@ -123,6 +123,25 @@ func TestEvent_SetProperty(t *testing.T) {
 			t.Errorf("Expected %v, got %v", expected, e)
 		}
 	})
+	t.Run("same name", func(t *testing.T) {
+		e := Event{
+			Properties: []EventProperty{
+				{Name: "one", StringValue: "one"},
+				{Name: "two", IntegerValue: 2},
+			},
+		}
+		expected := []EventProperty{
+			{Name: "one", StringValue: "one"},
+			{Name: "two", IntegerValue: 2},
+			{Name: "one", BoolValue: true},
+		}
+		if err := e.AddProperty("one", true); err != nil {
+			t.Errorf("Unexpected error %v", err)
+		}
+		if !reflect.DeepEqual(expected, e.Properties) {
+			t.Errorf("Expected %v, got %v", expected, e.Properties)
+		}
+	})
 	t.Run("error", func(t *testing.T) {
 		e := Event{
 			APIKey: "foo-bar-baz",
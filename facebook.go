@@ -27,11 +27,22 @@ type FacebookMessage struct {
 	Fields  *FacebookFields
 	Payload interface{}
 	APIKey  string
+
+	config *config
 }
 
 // MarshalJSON ensures the message is merged with the metadata in the way that
-// Chatbase expects it to be
+// Chatbase expects it to be. Typed payloads are serialized directly, any other
+// payload is merged with the metadata using an intermediate map
 func (f FacebookMessage) MarshalJSON() ([]byte, error) {
+	switch event := f.Payload.(type) {
+	case *MessengerEvent:
+		if event != nil {
+			return json.Marshal(typedFacebookMessage{event, f.Fields})
+		}
+	case MessengerEvent:
+		return json.Marshal(typedFacebookMessage{&event, f.Fields})
+	}
 	intermediate, intermediateErr := json.Marshal(f.Payload)
 	if intermediateErr != nil {
 		return nil, intermediateErr
@@ -84,14 +95,14 @@ func (f *FacebookMessage) SetVersion(v string) *FacebookMessage {
 
 // Submit tries to deliver a single Facebook message to chatbase
 func (f *FacebookMessage) Submit() (*MessageResponse, error) {
-	return postSingleFacebookItem(f, f.APIKey, facebookMessageEndpoint)
+	return f.submit(context.Background())
 }
 
 // SubmitWithContext tries to deliver a single Facebook message to chatbase
 // considering the given context's deadline
 func (f *FacebookMessage) SubmitWithContext(ctx context.Context) (*MessageResponse, error) {
 	data, err := resultWithContext(ctx, func() (interface{}, error) {
-		return f.Submit()
+		return f.submit(ctx)
 	})
 	if err != nil {
 		return nil, err
@@ -102,6 +113,10 @@ func (f *FacebookMessage) SubmitWithContext(ctx context.Context) (*MessageRespon
 	return nil, errBadData
 }
 
+func (f *FacebookMessage) submit(ctx context.Context) (*MessageResponse, error) {
+	return postSingleFacebookItem(ctx, f.config, f, f.APIKey, facebookMessageEndpoint)
+}
+
 // FacebookMessages is a collection of FacecbookMessage
 type FacebookMessages []FacebookMessage
 
@@ -125,18 +140,14 @@ func (f *FacebookMessages) Append(addition ...*FacebookMessage) *FacebookMessage
 // Submit tries to deliver the set of messages to Chatbase. The collection
 // cannot contain messages using different API keys
 func (f *FacebookMessages) Submit() (*MessagesResponse, error) {
-	if len(*f) == 0 {
-		return nil, errors.New("cannot submit empty collection")
-	}
-	apiKey := (*f)[0].APIKey
-	return postMultipleFacebookItems(f, apiKey, facebookMessagesEndpoint)
+	return f.submit(context.Background())
 }
 
 // SubmitWithContext tries to deliver a single Facebook message to chatbase
 // considering the given context's deadline
 func (f *FacebookMessages) SubmitWithContext(ctx context.Context) (*MessagesResponse, error) {
 	data, err := resultWithContext(ctx, func() (interface{}, error) {
-		return f.Submit()
+		return f.submit(ctx)
 	})
 	if err != nil {
 		return nil, err
@@ -147,7 +158,20 @@ func (f *FacebookMessages) SubmitWithContext(ctx context.Context) (*MessagesResp
 	return nil, errBadData
 }
 
-func postFacebook(endpoint, apiKey string, v interface{}) (io.ReadCloser, error) {
+func (f *FacebookMessages) submit(ctx context.Context) (*MessagesResponse, error) {
+	if len(*f) == 0 {
+		return nil, errors.New("cannot submit empty collection")
+	}
+	cfg, err := collectionConfig(len(*f), func(i int) *config {
+		return (*f)[i].config
+	})
+	if err != nil {
+		return nil, err
+	}
+	return postMultipleFacebookItems(ctx, cfg, f, (*f)[0].APIKey, facebookMessagesEndpoint)
+}
+
+func postFacebook(ctx context.Context, cfg *config, endpoint, apiKey string, v interface{}) (io.ReadCloser, error) {
 	ep, epErr := augmentURL(endpoint, map[string]string{
 		"api_key": apiKey,
 	})
@@ -155,22 +179,22 @@ func postFacebook(endpoint, apiKey string, v interface{}) (io.ReadCloser, error)
 		return nil, epErr
 	}
 
-	body, err := apiPost(ep, v)
+	body, err := apiPost(ctx, cfg, ep, v)
 	if err != nil {
 		return nil, err
 	}
 	return body, nil
 }
 
-func postSingleFacebookItem(v interface{}, apiKey, endpoint string) (*MessageResponse, error) {
+func postSingleFacebookItem(ctx context.Context, cfg *config, v interface{}, apiKey, endpoint string) (*MessageResponse, error) {
 	return newMessageResponse(func() (io.ReadCloser, error) {
-		return postFacebook(endpoint, apiKey, v)
+		return postFacebook(ctx, cfg, endpoint, apiKey, v)
 	})
 }
 
-func postMultipleFacebookItems(v interface{}, apiKey, endpoint string) (*MessagesResponse, error) {
+func postMultipleFacebookItems(ctx context.Context, cfg *config, v interface{}, apiKey, endpoint string) (*MessagesResponse, error) {
 	return newMessagesResponse(func() (io.ReadCloser, error) {
-		return postFacebook(endpoint, apiKey, v)
+		return postFacebook(ctx, cfg, endpoint, apiKey, v)
 	})
 }
 
@@ -181,6 +205,8 @@ type FacebookRequestResponse struct {
 	Request  interface{}     `json:"request_body"`
 	Response interface{}     `json:"response_body"`
 	Fields   *FacebookFields `json:"chatbase_fields"`
+
+	config *config
 }
 
 // SetIntent adds an optional "intent" value to the pair
@@ -221,14 +247,14 @@ func (f *FacebookRequestResponse) SetVersion(v string) *FacebookRequestResponse
 
 // Submit tries to deliver the pair to Chatbase
 func (f *FacebookRequestResponse) Submit() (*MessageResponse, error) {
-	return postSingleFacebookItem(f, f.APIKey, facebookRequestEndpoint)
+	return f.submit(context.Background())
 }
 
 // SubmitWithContext tries to deliver the pair to Chatbase
 // considering the given context's deadline
 func (f *FacebookRequestResponse) SubmitWithContext(ctx context.Context) (*MessageResponse, error) {
 	data, err := resultWithContext(ctx, func() (interface{}, error) {
-		return f.Submit()
+		return f.submit(ctx)
 	})
 	if err != nil {
 		return nil, err
@@ -239,6 +265,10 @@ func (f *FacebookRequestResponse) SubmitWithContext(ctx context.Context) (*Messa
 	return nil, errBadData
 }
 
+func (f *FacebookRequestResponse) submit(ctx context.Context) (*MessageResponse, error) {
+	return postSingleFacebookItem(ctx, f.config, f, f.APIKey, facebookRequestEndpoint)
+}
+
 // FacebookRequestResponses is a collection of FacebookRequestResponse
 type FacebookRequestResponses []FacebookRequestResponse
 
@@ -253,18 +283,14 @@ func (f FacebookRequestResponses) MarshalJSON() ([]byte, error) {
 // Submit tries to send the collection of request/response pairs to Chatbase.
 // The collection should not contain messages using different API keys
 func (f *FacebookRequestResponses) Submit() (*MessagesResponse, error) {
-	if len(*f) == 0 {
-		return nil, errors.New("cannot submit empty collection")
-	}
-	apiKey := (*f)[0].APIKey
-	return postMultipleFacebookItems(f, apiKey, facebookRequestsEndpoint)
+	return f.submit(context.Background())
 }
 
 // SubmitWithContext tries to send the collection of request/response pairs to Chatbase
 // considering the given context's deadline
 func (f *FacebookRequestResponses) SubmitWithContext(ctx context.Context) (*MessagesResponse, error) {
 	data, err := resultWithContext(ctx, func() (interface{}, error) {
-		return f.Submit()
+		return f.submit(ctx)
 	})
 	if err != nil {
 		return nil, err
@@ -275,6 +301,19 @@ func (f *FacebookRequestResponses) SubmitWithContext(ctx context.Context) (*Mess
 	return nil, errBadData
 }
 
+func (f *FacebookRequestResponses) submit(ctx context.Context) (*MessagesResponse, error) {
+	if len(*f) == 0 {
+		return nil, errors.New("cannot submit empty collection")
+	}
+	cfg, err := collectionConfig(len(*f), func(i int) *config {
+		return (*f)[i].config
+	})
+	if err != nil {
+		return nil, err
+	}
+	return postMultipleFacebookItems(ctx, cfg, f, (*f)[0].APIKey, facebookRequestsEndpoint)
+}
+
 // Append adds additional messages to the collection. The collection should not
 // contain messages using different API keys
 func (f *FacebookRequestResponses) Append(addition ...*FacebookRequestResponse) *FacebookRequestResponses {
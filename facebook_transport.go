@@ -0,0 +1,184 @@
+package chatbase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults used by FacebookTransport when the respective fields are not set
+const (
+	DefaultBatchSize     = 100
+	DefaultFlushInterval = 5 * time.Second
+	DefaultQueueSize     = 1000
+)
+
+// facebookTransportPipeline is the pipeline name passed to a PipelineObserver
+const facebookTransportPipeline = "facebook_transport"
+
+type facebookFieldsKey struct{}
+
+// ContextWithFacebookFields returns a copy of ctx carrying the given metadata.
+// Send API requests made using this context will be recorded using these
+// fields by FacebookTransport
+func ContextWithFacebookFields(ctx context.Context, fields FacebookFields) context.Context {
+	return context.WithValue(ctx, facebookFieldsKey{}, fields)
+}
+
+// FacebookFieldsFromContext returns the metadata stored in ctx, if any
+func FacebookFieldsFromContext(ctx context.Context) (FacebookFields, bool) {
+	fields, ok := ctx.Value(facebookFieldsKey{}).(FacebookFields)
+	return fields, ok
+}
+
+// FacebookTransport is an http.RoundTripper for the client used for calling
+// the Messenger Send API. It records each successful call to "/me/messages"
+// as a FacebookRequestResponse and submits them to Chatbase in batches. Any
+// other request is passed through unchanged.
+type FacebookTransport struct {
+	Client *Client
+	// Base is the RoundTripper used for performing the actual requests,
+	// http.DefaultTransport is used when it is nil
+	Base http.RoundTripper
+	// BatchSize is the maximum number of pairs submitted in a single request
+	BatchSize int
+	// FlushInterval is the maximum time a pair is waiting for submission
+	FlushInterval time.Duration
+	// QueueSize is the number of pairs that can wait for submission. Pairs
+	// are dropped when the queue is full
+	QueueSize int
+	// ErrorHandler is called when recording or submitting pairs fails.
+	// Errors are discarded when it is nil
+	ErrorHandler func(error)
+
+	once    sync.Once
+	batcher *batcher
+}
+
+// FacebookTransport returns a new FacebookTransport using the client
+// and the given base transport
+func (c *Client) FacebookTransport(base http.RoundTripper) *FacebookTransport {
+	return &FacebookTransport{
+		Client: c,
+		Base:   base,
+	}
+}
+
+// RoundTrip performs the request using the base transport and queues
+// Send API calls for submission
+func (t *FacebookTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, "/me/messages") {
+		return t.base().RoundTrip(req)
+	}
+
+	var requestBody []byte
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		requestBody = b
+		req = req.Clone(req.Context())
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	}
+
+	res, err := t.base().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBody, readErr := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if readErr != nil {
+		return nil, readErr
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(responseBody))
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return res, nil
+	}
+	if !json.Valid(requestBody) || !json.Valid(responseBody) {
+		t.handleError(fmt.Errorf("could not record send api call to %v: bodies are not valid json", req.URL))
+		return res, nil
+	}
+	pair := t.Client.FacebookRequestResponse(json.RawMessage(requestBody), json.RawMessage(responseBody))
+	if fields, ok := FacebookFieldsFromContext(req.Context()); ok {
+		// the context only carries a version when it overrides the default
+		if fields.Version == "" && pair.Fields != nil {
+			fields.Version = pair.Fields.Version
+		}
+		pair.Fields = &fields
+	}
+	cfg := t.config()
+	cfg.queued(facebookTransportPipeline, 1)
+	if !t.queue().add(pair) {
+		cfg.queued(facebookTransportPipeline, -1)
+		cfg.dropped(facebookTransportPipeline, "queue is full")
+		t.handleError(fmt.Errorf("dropped send api call to %v: queue is full", req.URL))
+	}
+	return res, nil
+}
+
+// Close submits all pending pairs. Calls made after Close has been
+// called will not be recorded anymore.
+func (t *FacebookTransport) Close() error {
+	t.queue().close()
+	return nil
+}
+
+func (t *FacebookTransport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+func (t *FacebookTransport) queue() *batcher {
+	t.once.Do(func() {
+		size, interval, capacity := t.BatchSize, t.FlushInterval, t.QueueSize
+		if size <= 0 {
+			size = DefaultBatchSize
+		}
+		if interval <= 0 {
+			interval = DefaultFlushInterval
+		}
+		if capacity <= 0 {
+			capacity = DefaultQueueSize
+		}
+		t.batcher = newBatcher(capacity, size, interval, t.submit)
+	})
+	return t.batcher
+}
+
+func (t *FacebookTransport) submit(items []interface{}) {
+	t.config().queued(facebookTransportPipeline, -len(items))
+	pairs := FacebookRequestResponses{}
+	for _, item := range items {
+		pairs.Append(item.(*FacebookRequestResponse))
+	}
+	res, err := pairs.Submit()
+	if err == nil && !res.Status.OK() {
+		err = fmt.Errorf("submitting send api calls failed: %s", res.Reason)
+	}
+	t.handleError(err)
+}
+
+func (t *FacebookTransport) config() *config {
+	if t.Client == nil {
+		return nil
+	}
+	return t.Client.config()
+}
+
+func (t *FacebookTransport) handleError(err error) {
+	if err != nil && t.ErrorHandler != nil {
+		t.ErrorHandler(err)
+	}
+}
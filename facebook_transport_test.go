@@ -0,0 +1,183 @@
+package chatbase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFacebookTransport(t *testing.T) {
+	oldEndpoint := facebookRequestsEndpoint
+	defer func() { facebookRequestsEndpoint = oldEndpoint }()
+
+	submitted := make(chan []map[string]interface{}, 1)
+	chatbaseServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Messages []map[string]interface{} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.Write([]byte(`{"all_succeeded":true,"status":200}`))
+		submitted <- payload.Messages
+	}))
+	defer chatbaseServer.Close()
+	facebookRequestsEndpoint = chatbaseServer.URL
+
+	graph := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		if r.URL.Path == "/v2.6/me/messages" && !strings.Contains(string(b), "hello") {
+			http.Error(w, "body was not passed on", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"recipient_id":"u1","message_id":"mid.1"}`))
+	}))
+	defer graph.Close()
+
+	transport := New("key").FacebookTransport(nil)
+	transport.BatchSize = 2
+	httpClient := &http.Client{Transport: transport}
+
+	send := func(ctx context.Context, path string) {
+		req, _ := http.NewRequest(http.MethodPost, graph.URL+path, strings.NewReader(`{"recipient":{"id":"u1"},"message":{"text":"hello"}}`))
+		res, err := httpClient.Do(req.WithContext(ctx))
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		defer res.Body.Close()
+		b, _ := ioutil.ReadAll(res.Body)
+		if res.StatusCode != http.StatusOK || !strings.Contains(string(b), "mid.1") {
+			t.Errorf("Unexpected response %v %s", res.StatusCode, b)
+		}
+	}
+
+	send(context.Background(), "/v2.6/me/messages")
+	send(context.Background(), "/v2.6/me/messenger_profile")
+	send(ContextWithFacebookFields(context.Background(), FacebookFields{Intent: "greet"}), "/v2.6/me/messages")
+
+	select {
+	case pairs := <-submitted:
+		if len(pairs) != 2 {
+			t.Fatalf("Unexpected pairs %v", pairs)
+		}
+		request := pairs[0]["request_body"].(map[string]interface{})
+		if request["message"].(map[string]interface{})["text"] != "hello" {
+			t.Errorf("Unexpected request body %v", request)
+		}
+		response := pairs[0]["response_body"].(map[string]interface{})
+		if response["message_id"] != "mid.1" {
+			t.Errorf("Unexpected response body %v", response)
+		}
+		if pairs[0]["chatbase_fields"] != nil {
+			t.Errorf("Unexpected fields %v", pairs[0]["chatbase_fields"])
+		}
+		if fields := pairs[1]["chatbase_fields"].(map[string]interface{}); fields["intent"] != "greet" {
+			t.Errorf("Unexpected fields %v", fields)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected pairs to be submitted")
+	}
+	transport.Close()
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestFacebookTransport_ResponseError(t *testing.T) {
+	transport := New("key").FacebookTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(failingReader{})}, nil
+	}))
+	defer transport.Close()
+	req := httptest.NewRequest(http.MethodPost, "https://graph.facebook.com/v2.6/me/messages", strings.NewReader(`{}`))
+	res, err := transport.RoundTrip(req)
+	if res != nil || err == nil {
+		t.Errorf("Expected error without response, got %v %v", res, err)
+	}
+}
+
+func TestFacebookFieldsFromContext(t *testing.T) {
+	if _, ok := FacebookFieldsFromContext(context.Background()); ok {
+		t.Error("Expected no fields in empty context")
+	}
+	ctx := ContextWithFacebookFields(context.Background(), FacebookFields{Intent: "test", NotHandled: true})
+	fields, ok := FacebookFieldsFromContext(ctx)
+	if !ok || fields.Intent != "test" || !fields.NotHandled {
+		t.Errorf("Unexpected fields %v", fields)
+	}
+}
+
+func TestFacebookTransport_DefaultVersion(t *testing.T) {
+	var payload struct {
+		Messages []struct {
+			Fields FacebookFields `json:"chatbase_fields"`
+		} `json:"messages"`
+	}
+	SetAPITransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		json.NewDecoder(req.Body).Decode(&payload)
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(`{"all_succeeded":true,"status":200}`))}, nil
+	}))
+	defer SetAPITransport(nil)
+
+	transport := New("key", WithVersion("1.2.0")).FacebookTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(`{"message_id":"mid.1"}`))}, nil
+	}))
+	for _, fields := range []FacebookFields{{Intent: "greet"}, {Intent: "bye", Version: "2.0.0"}} {
+		req := httptest.NewRequest(http.MethodPost, "https://graph.facebook.com/v2.6/me/messages", strings.NewReader(`{}`))
+		if _, err := transport.RoundTrip(req.WithContext(ContextWithFacebookFields(context.Background(), fields))); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+	transport.Close()
+
+	expected := []FacebookFields{{Intent: "greet", Version: "1.2.0"}, {Intent: "bye", Version: "2.0.0"}}
+	if len(payload.Messages) != len(expected) {
+		t.Fatalf("Unexpected payload %#v", payload)
+	}
+	for i, m := range payload.Messages {
+		if m.Fields != expected[i] {
+			t.Errorf("Expected %#v, got %#v", expected[i], m.Fields)
+		}
+	}
+}
+
+func TestFacebookTransport_Request(t *testing.T) {
+	requests := 0
+	SetAPITransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(`{"all_succeeded":true,"status":200}`))}, nil
+	}))
+	defer SetAPITransport(nil)
+
+	transport := New("key").FacebookTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if b, _ := ioutil.ReadAll(req.Body); string(b) != `{}` {
+			t.Errorf("Expected body to be passed on, got %s", b)
+		}
+		return &http.Response{StatusCode: http.StatusBadRequest, Body: ioutil.NopCloser(strings.NewReader(`{"error":{"message":"invalid"}}`))}, nil
+	}))
+	req := httptest.NewRequest(http.MethodPost, "https://graph.facebook.com/v2.6/me/messages", strings.NewReader(`{}`))
+	body := req.Body
+	res, err := transport.RoundTrip(req)
+	if err != nil || res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Unexpected result %v %v", res, err)
+	}
+	if req.Body != body {
+		t.Error("Expected request of the caller not to be modified")
+	}
+	transport.Close()
+	if requests != 0 {
+		t.Errorf("Expected failed call not to be recorded, got %d requests", requests)
+	}
+}
@@ -0,0 +1,147 @@
+package chatbase
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// FacebookWebhook is the payload sent to a Messenger webhook
+type FacebookWebhook struct {
+	Object string                 `json:"object"`
+	Entry  []FacebookWebhookEntry `json:"entry"`
+}
+
+// FacebookWebhookEntry is a single entry of a Messenger webhook payload
+type FacebookWebhookEntry struct {
+	ID        string            `json:"id"`
+	Time      int64             `json:"time"`
+	Messaging []json.RawMessage `json:"messaging"`
+}
+
+// FacebookWebhookHandler handles requests to a Messenger webhook. It answers
+// the subscription challenge, verifies the payload signature and forwards
+// all messaging events to Chatbase before passing them on to the callback.
+// The handler fails closed: subscriptions are rejected when VerifyToken is
+// empty and payloads are rejected when AppSecret is empty
+type FacebookWebhookHandler struct {
+	Client *Client
+	// VerifyToken is the token configured when subscribing to the webhook
+	VerifyToken string
+	// AppSecret is used for verifying the payload signature
+	AppSecret string
+	// Callback receives all entries of a verified webhook payload
+	Callback func(r *http.Request, entries []FacebookWebhookEntry)
+	// ErrorHandler is called when forwarding the events to Chatbase
+	// fails. Errors are discarded when it is nil
+	ErrorHandler func(error)
+}
+
+// FacebookWebhookHandler returns a new handler for Messenger webhooks
+// using the client and the given tokens
+func (c *Client) FacebookWebhookHandler(verifyToken, appSecret string, callback func(*http.Request, []FacebookWebhookEntry)) *FacebookWebhookHandler {
+	return &FacebookWebhookHandler{
+		Client:      c,
+		VerifyToken: verifyToken,
+		AppSecret:   appSecret,
+		Callback:    callback,
+	}
+}
+
+func (h *FacebookWebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.verifySubscription(w, r)
+	case http.MethodPost:
+		h.receive(w, r)
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *FacebookWebhookHandler) verifySubscription(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if h.VerifyToken == "" || q.Get("hub.mode") != "subscribe" || q.Get("hub.verify_token") != h.VerifyToken {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	w.Write([]byte(q.Get("hub.challenge")))
+}
+
+func (h *FacebookWebhookHandler) receive(w http.ResponseWriter, r *http.Request) {
+	if h.AppSecret == "" {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	body, bodyErr := ioutil.ReadAll(r.Body)
+	if bodyErr != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	if err := verifyHubSignature(r.Header, body, h.AppSecret); err != nil {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	var webhook FacebookWebhook
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	messages := FacebookMessages{}
+	for _, entry := range webhook.Entry {
+		for _, event := range entry.Messaging {
+			messages.Append(h.Client.FacebookMessage(event))
+		}
+	}
+	if len(messages) == 1 {
+		SubmitAsync(h.ErrorHandler, &messages[0])
+	} else {
+		SubmitAsync(h.ErrorHandler, &messages)
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if h.Callback != nil {
+		h.Callback(r, webhook.Entry)
+	}
+	w.Write([]byte("EVENT_RECEIVED"))
+}
+
+var errBadSignature = errors.New("payload signature does not match")
+
+// verifyHubSignature checks the payload signature sent by Facebook, preferring
+// the SHA256 based header over the legacy SHA1 one if present
+func verifyHubSignature(header http.Header, body []byte, secret string) error {
+	var (
+		signature string
+		algorithm func() hash.Hash
+		prefix    string
+	)
+	if s := header.Get("X-Hub-Signature-256"); s != "" {
+		signature, algorithm, prefix = s, sha256.New, "sha256="
+	} else {
+		signature, algorithm, prefix = header.Get("X-Hub-Signature"), sha1.New, "sha1="
+	}
+	if !strings.HasPrefix(signature, prefix) {
+		return errBadSignature
+	}
+	expected, decodeErr := hex.DecodeString(strings.TrimPrefix(signature, prefix))
+	if decodeErr != nil {
+		return errBadSignature
+	}
+	mac := hmac.New(algorithm, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return errBadSignature
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package chatbase
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFacebookWebhookHandler_Subscription(t *testing.T) {
+	h := New("key").FacebookWebhookHandler("verify-me", "app-secret", nil)
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode int
+		expectedBody string
+	}{
+		{"default", "?hub.mode=subscribe&hub.verify_token=verify-me&hub.challenge=12345", http.StatusOK, "12345"},
+		{"bad token", "?hub.mode=subscribe&hub.verify_token=nope&hub.challenge=12345", http.StatusForbidden, "Forbidden\n"},
+		{"bad mode", "?hub.mode=unsubscribe&hub.verify_token=verify-me&hub.challenge=12345", http.StatusForbidden, "Forbidden\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+test.query, nil))
+			if rec.Code != test.expectedCode {
+				t.Errorf("Expected status %v, got %v", test.expectedCode, rec.Code)
+			}
+			if b := rec.Body.String(); b != test.expectedBody {
+				t.Errorf("Expected body %v, got %v", test.expectedBody, b)
+			}
+		})
+	}
+
+	t.Run("empty verify token", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h := New("key").FacebookWebhookHandler("", "app-secret", nil)
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?hub.mode=subscribe&hub.verify_token=&hub.challenge=12345", nil))
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("Expected status %v, got %v", http.StatusForbidden, rec.Code)
+		}
+	})
+}
+
+func TestFacebookWebhookHandler_EmptyAppSecret(t *testing.T) {
+	called := false
+	h := New("key").FacebookWebhookHandler("verify-me", "", func(r *http.Request, e []FacebookWebhookEntry) {
+		called = true
+	})
+	body := `{"object":"page","entry":[{"id":"1","time":1,"messaging":[{"sender":{"id":"u1"}}]}]}`
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	if rec.Code != http.StatusForbidden || called {
+		t.Errorf("Expected unsigned payload to be rejected, got %v", rec.Code)
+	}
+}
+
+func TestFacebookWebhookHandler_Receive(t *testing.T) {
+	oldMessage, oldMessages := facebookMessageEndpoint, facebookMessagesEndpoint
+	defer func() { facebookMessageEndpoint, facebookMessagesEndpoint = oldMessage, oldMessages }()
+
+	type forwarded struct {
+		path    string
+		apiKey  string
+		payload map[string]interface{}
+	}
+	received := make(chan forwarded, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.Write([]byte(`{"status":200}`))
+		received <- forwarded{r.URL.Path, r.URL.Query().Get("api_key"), payload}
+	}))
+	defer ts.Close()
+	facebookMessageEndpoint = ts.URL + "/single"
+	facebookMessagesEndpoint = ts.URL + "/batch"
+
+	sign := func(body string) string {
+		mac := hmac.New(sha1.New, []byte("app-secret"))
+		mac.Write([]byte(body))
+		return "sha1=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	sign256 := func(body string) string {
+		mac := hmac.New(sha256.New, []byte("app-secret"))
+		mac.Write([]byte(body))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	single := `{"object":"page","entry":[{"id":"1","time":1,"messaging":[{"sender":{"id":"u1"},"message":{"text":"hi"}}]}]}`
+	multiple := `{"object":"page","entry":[{"id":"1","time":1,"messaging":[{"sender":{"id":"u1"}}]},{"id":"1","time":2,"messaging":[{"sender":{"id":"u2"}}]}]}`
+
+	tests := []struct {
+		name           string
+		body           string
+		header         http.Header
+		expectedCode   int
+		expectCallback bool
+		expectedPath   string
+		expectedCount  int
+	}{
+		{"single", single, http.Header{"X-Hub-Signature": {sign(single)}}, http.StatusOK, true, "/single", 1},
+		{"batch", multiple, http.Header{"X-Hub-Signature-256": {sign256(multiple)}}, http.StatusOK, true, "/batch", 2},
+		{"bad signature", single, http.Header{"X-Hub-Signature": {sign("zalgo")}}, http.StatusForbidden, false, "", 0},
+		{"missing signature", single, http.Header{}, http.StatusForbidden, false, "", 0},
+		{"bad payload", "{{", http.Header{"X-Hub-Signature": {sign("{{")}}, http.StatusBadRequest, false, "", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var entries []FacebookWebhookEntry
+			h := New("key").FacebookWebhookHandler("verify-me", "app-secret", func(r *http.Request, e []FacebookWebhookEntry) {
+				b, _ := ioutil.ReadAll(r.Body)
+				if string(b) != test.body {
+					t.Errorf("Expected body to be restored, got %v", string(b))
+				}
+				entries = e
+			})
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(test.body))
+			req.Header = test.header
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != test.expectedCode {
+				t.Errorf("Expected status %v, got %v", test.expectedCode, rec.Code)
+			}
+			if test.expectCallback != (entries != nil) {
+				t.Errorf("Unexpected callback entries %v", entries)
+			}
+			if test.expectedPath == "" {
+				return
+			}
+			select {
+			case f := <-received:
+				if f.path != test.expectedPath || f.apiKey != "key" {
+					t.Errorf("Unexpected request to %v using %v", f.path, f.apiKey)
+				}
+				count := 1
+				if messages, ok := f.payload["messages"].([]interface{}); ok {
+					count = len(messages)
+				}
+				if count != test.expectedCount {
+					t.Errorf("Expected %d events, got %d", test.expectedCount, count)
+				}
+			case <-time.After(time.Second):
+				t.Error("Expected events to be forwarded")
+			}
+		})
+	}
+}
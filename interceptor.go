@@ -0,0 +1,122 @@
+package chatbase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+)
+
+// APIRequest describes a call to the Chatbase API
+type APIRequest struct {
+	Method   string
+	Endpoint string
+	// Kind identifies the endpoint independent of its URL, e.g. "message",
+	// "events" or "facebook_request_responses"
+	Kind string
+	// Items is the number of items contained in the payload
+	Items int
+	// Payload is the typed value that is being submitted, e.g. *Message,
+	// *Events or *FacebookMessages. It is serialized after all interceptors
+	// have been called, so it can be modified or replaced
+	Payload interface{}
+	Header  http.Header
+}
+
+// APIResponse is the raw response to a call to the Chatbase API
+type APIResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// APICall performs a call to the Chatbase API
+type APICall func(ctx context.Context, req *APIRequest) (*APIResponse, error)
+
+// Interceptor wraps calls to the Chatbase API. It can modify the request
+// before passing it to next, inspect or modify the response returned by next
+// or return a response without calling next at all. Returning neither a
+// response nor an error fails the call.
+type Interceptor func(ctx context.Context, req *APIRequest, next APICall) (*APIResponse, error)
+
+// WithInterceptors adds interceptors that are called for all submissions
+// of payloads created by the client. Interceptors are called in the given
+// order, the first one being the outermost.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(c *config) {
+		c.interceptors = append(c.interceptors, interceptors...)
+	}
+}
+
+// errNoResponse is returned when an interceptor returns neither a
+// response nor an error
+var errNoResponse = errors.New("interceptor returned no response")
+
+// chain returns the func performing API calls, wrapped
+// in the configured interceptors
+func (c *config) chain() APICall {
+	if c == nil {
+		return send
+	}
+	call := send
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		interceptor, next := c.interceptors[i], call
+		call = func(ctx context.Context, req *APIRequest) (*APIResponse, error) {
+			res, err := interceptor(ctx, req, next)
+			if res == nil && err == nil {
+				err = errNoResponse
+			}
+			return res, err
+		}
+	}
+	return call
+}
+
+// sentKey is the context key of the flag set by send once a request
+// is handed to the http.Client
+type sentKey struct{}
+
+// Sent reports whether the request of the given context has been handed to
+// the http.Client. Interceptors can check it after next has returned, it is
+// false when the request failed before or was answered by another interceptor
+func Sent(ctx context.Context) bool {
+	sent, ok := ctx.Value(sentKey{}).(*bool)
+	return ok && *sent
+}
+
+// markSent records that the request of the given context is being sent
+func markSent(ctx context.Context) {
+	if sent, ok := ctx.Value(sentKey{}).(*bool); ok {
+		*sent = true
+	}
+}
+
+// send performs the actual HTTP request
+func send(ctx context.Context, req *APIRequest) (*APIResponse, error) {
+	payload, payloadErr := json.Marshal(req.Payload)
+	if payloadErr != nil {
+		return nil, payloadErr
+	}
+	httpReq, httpReqErr := http.NewRequestWithContext(ctx, req.Method, req.Endpoint, bytes.NewReader(payload))
+	if httpReqErr != nil {
+		return nil, httpReqErr
+	}
+	httpReq.Header = req.Header
+	markSent(ctx)
+	res, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, bodyErr := ioutil.ReadAll(res.Body)
+	if bodyErr != nil {
+		return nil, bodyErr
+	}
+	return &APIResponse{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       body,
+	}, nil
+}
@@ -0,0 +1,145 @@
+package chatbase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestWithInterceptors(t *testing.T) {
+	oldMessage, oldFacebook := messageEndpoint, facebookMessagesEndpoint
+	defer func() { messageEndpoint, facebookMessagesEndpoint = oldMessage, oldFacebook }()
+
+	var received []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		received = append(received, r.Header.Get("X-Team")+" "+string(b))
+		w.Write([]byte(`{"message_id":"123","status":200}`))
+	}))
+	defer ts.Close()
+	messageEndpoint = ts.URL
+	facebookMessagesEndpoint = ts.URL
+
+	t.Run("order and mutation", func(t *testing.T) {
+		received = nil
+		var calls []string
+		record := func(name string) Interceptor {
+			return func(ctx context.Context, req *APIRequest, next APICall) (*APIResponse, error) {
+				calls = append(calls, name+" before")
+				res, err := next(ctx, req)
+				calls = append(calls, name+" after")
+				return res, err
+			}
+		}
+		inject := func(ctx context.Context, req *APIRequest, next APICall) (*APIResponse, error) {
+			req.Header.Set("X-Team", "support")
+			if m, ok := req.Payload.(*Message); ok {
+				m.SetVersion("v2")
+			}
+			return next(ctx, req)
+		}
+		c := New("key", WithInterceptors(record("outer"), record("inner")), WithInterceptors(inject))
+		res, err := c.UserMessage("user", PlatformWeb).SetMessage("hi").Submit()
+		if err != nil || !res.Status.OK() {
+			t.Fatalf("Unexpected result %v %v", res, err)
+		}
+		expected := []string{"outer before", "inner before", "inner after", "outer after"}
+		if !reflect.DeepEqual(expected, calls) {
+			t.Errorf("Expected %v, got %v", expected, calls)
+		}
+		if len(received) != 1 {
+			t.Fatalf("Expected 1 request, got %v", received)
+		}
+		var m Message
+		json.Unmarshal([]byte(received[0][len("support "):]), &m)
+		if received[0][:len("support")] != "support" || m.Version != "v2" {
+			t.Errorf("Unexpected request %v", received[0])
+		}
+	})
+
+	t.Run("short circuit", func(t *testing.T) {
+		received = nil
+		sent := true
+		observe := func(ctx context.Context, req *APIRequest, next APICall) (*APIResponse, error) {
+			res, err := next(ctx, req)
+			sent = Sent(ctx)
+			return res, err
+		}
+		c := New("key", WithInterceptors(observe, func(ctx context.Context, req *APIRequest, next APICall) (*APIResponse, error) {
+			return &APIResponse{StatusCode: http.StatusOK, Body: []byte(`{"message_id":"cached","status":200}`)}, nil
+		}))
+		res, err := c.UserMessage("user", PlatformWeb).Submit()
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if sent {
+			t.Error("Expected short circuited request not to be sent")
+		}
+		if res.MessageID != "cached" || len(received) != 0 {
+			t.Errorf("Unexpected response %v, requests: %v", res, received)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		c := New("key", WithInterceptors(func(ctx context.Context, req *APIRequest, next APICall) (*APIResponse, error) {
+			return nil, errors.New("denied")
+		}))
+		if _, err := c.UserMessage("user", PlatformWeb).Submit(); err == nil || err.Error() != "denied" {
+			t.Errorf("Expected interceptor error, got %v", err)
+		}
+	})
+
+	t.Run("no response", func(t *testing.T) {
+		var seen error
+		c := New("key", WithInterceptors(func(ctx context.Context, req *APIRequest, next APICall) (*APIResponse, error) {
+			res, err := next(ctx, req)
+			seen = err
+			return res, err
+		}, func(ctx context.Context, req *APIRequest, next APICall) (*APIResponse, error) {
+			return nil, nil
+		}))
+		if _, err := c.UserMessage("user", PlatformWeb).SubmitWithContext(context.Background()); err != errNoResponse {
+			t.Errorf("Expected errNoResponse, got %v", err)
+		}
+		if seen != errNoResponse {
+			t.Errorf("Expected outer interceptor to see errNoResponse, got %v", seen)
+		}
+	})
+
+	t.Run("facebook", func(t *testing.T) {
+		received = nil
+		var seen interface{}
+		var status int
+		var kind string
+		var items int
+		var sent bool
+		c := New("key", WithInterceptors(func(ctx context.Context, req *APIRequest, next APICall) (*APIResponse, error) {
+			seen, kind, items = req.Payload, req.Kind, req.Items
+			res, err := next(ctx, req)
+			if res != nil {
+				status = res.StatusCode
+			}
+			sent = Sent(ctx)
+			return res, err
+		}))
+		messages := FacebookMessages{}
+		messages.Append(c.FacebookMessage(map[string]string{"hello": "world"}))
+		if _, err := messages.Submit(); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if p, ok := seen.(*FacebookMessages); !ok || len(*p) != 1 {
+			t.Errorf("Unexpected payload %#v", seen)
+		}
+		if status != http.StatusOK || len(received) != 1 || !sent {
+			t.Errorf("Unexpected status %v, requests: %v", status, received)
+		}
+		if kind != "facebook_messages" || items != 1 {
+			t.Errorf("Unexpected kind %v with %d items", kind, items)
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package line
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+// DefaultEndpoint is the base URL of the Messaging API
+const DefaultEndpoint = "https://api.line.me"
+
+// MessagingClient sends messages using the reply and push API and records
+// them as agent messages. URI actions in templates are rewritten to Chatbase
+// tracking links before sending
+type MessagingClient struct {
+	Client             *chatbase.Client
+	ChannelAccessToken string
+	// HTTPClient is used for calling the Messaging API,
+	// http.DefaultClient is used when it is nil
+	HTTPClient *http.Client
+	// Endpoint is the base URL of the Messaging API,
+	// DefaultEndpoint is used when it is empty
+	Endpoint string
+	// ErrorHandler is called when sent messages cannot be recorded.
+	// Errors are discarded when it is nil
+	ErrorHandler func(error)
+}
+
+// NewMessagingClient returns a new MessagingClient using the given
+// client and channel access token
+func NewMessagingClient(client *chatbase.Client, channelAccessToken string) *MessagingClient {
+	return &MessagingClient{
+		Client:             client,
+		ChannelAccessToken: channelAccessToken,
+	}
+}
+
+// Reply sends the given messages as a reply to the event
+func (m *MessagingClient) Reply(ctx context.Context, e *Event, messages ...Message) error {
+	tracked, err := m.track(messages)
+	if err != nil {
+		return err
+	}
+	if err := m.call(ctx, "/v2/bot/message/reply", map[string]interface{}{
+		"replyToken": e.ReplyToken,
+		"messages":   tracked,
+	}); err != nil {
+		return err
+	}
+	m.record(e.Source.UserID, e.Source.SessionID(), tracked)
+	return nil
+}
+
+// Push sends the given messages to a user, group or room. The recipient
+// is used as user id when recording the messages
+func (m *MessagingClient) Push(ctx context.Context, to string, messages ...Message) error {
+	tracked, err := m.track(messages)
+	if err != nil {
+		return err
+	}
+	if err := m.call(ctx, "/v2/bot/message/push", map[string]interface{}{
+		"to":       to,
+		"messages": tracked,
+	}); err != nil {
+		return err
+	}
+	m.record(to, to, tracked)
+	return nil
+}
+
+// track returns copies of the given messages that use tracking links
+func (m *MessagingClient) track(messages []Message) ([]Message, error) {
+	tracked := make([]Message, len(messages))
+	for i, message := range messages {
+		if message.Template != nil {
+			template := copyTemplate(*message.Template)
+			message.Template = &template
+		}
+		if err := TrackLinks(m.Client, &message); err != nil {
+			return nil, err
+		}
+		tracked[i] = message
+	}
+	return tracked, nil
+}
+
+func (m *MessagingClient) call(ctx context.Context, path string, payload interface{}) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	endpoint := m.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint+path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+m.ChannelAccessToken)
+
+	httpClient := m.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("calling %s failed with status %v: %s", path, res.StatusCode, body)
+	}
+	return nil
+}
+
+func (m *MessagingClient) record(userID, sessionID string, messages []Message) {
+	collection := chatbase.Messages{}
+	for i := range messages {
+		collection.Append(AgentMessage(m.Client, userID, sessionID, &messages[i]))
+	}
+	chatbase.SubmitAsync(m.ErrorHandler, &collection)
+}
+
+func copyTemplate(t Template) Template {
+	t.Actions = append([]Action(nil), t.Actions...)
+	columns := make([]Column, len(t.Columns))
+	for i, column := range t.Columns {
+		column.Actions = append([]Action(nil), column.Actions...)
+		if column.DefaultAction != nil {
+			action := *column.DefaultAction
+			column.DefaultAction = &action
+		}
+		columns[i] = column
+	}
+	if t.Columns != nil {
+		t.Columns = columns
+	}
+	return t
+}
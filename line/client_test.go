@@ -0,0 +1,94 @@
+package line
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+func TestMessagingClient(t *testing.T) {
+	defer chatbase.SetAPITransport(nil)
+	requests := captureRequests()
+
+	var sent map[string]interface{}
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		sent = nil
+		json.NewDecoder(r.Body).Decode(&sent)
+		sent["path"] = r.URL.Path
+		w.Write([]byte("{}"))
+	}))
+	defer api.Close()
+
+	client := chatbase.New("key")
+	messaging := NewMessagingClient(client, "token")
+	messaging.Endpoint = api.URL
+
+	template := Message{
+		Type:     "template",
+		AltText:  "Read more",
+		Template: &Template{Type: "buttons", Text: "Read more", Actions: []Action{{Type: "uri", Label: "Open", URI: "https://example.net"}}},
+	}
+
+	t.Run("reply", func(t *testing.T) {
+		event := &Event{ReplyToken: "reply-token", Source: Source{Type: "group", UserID: "U1", GroupID: "G1"}}
+		if err := messaging.Reply(context.Background(), event, TextMessage("Hello"), template); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if sent["path"] != "/v2/bot/message/reply" || sent["replyToken"] != "reply-token" {
+			t.Errorf("Unexpected call %v", sent)
+		}
+		uri := sent["messages"].([]interface{})[1].(map[string]interface{})["template"].(map[string]interface{})["actions"].([]interface{})[0].(map[string]interface{})["uri"]
+		if !strings.HasPrefix(uri.(string), "https://chatbase.com/r?") {
+			t.Errorf("Expected tracking link, got %v", uri)
+		}
+		if template.Template.Actions[0].URI != "https://example.net" {
+			t.Error("Expected original message to be untouched")
+		}
+		select {
+		case r := <-requests:
+			if !strings.Contains(r, `"user_id":"U1"`) || !strings.Contains(r, `"session_id":"G1"`) || !strings.Contains(r, `"message":"Read more"`) {
+				t.Errorf("Unexpected request %v", r)
+			}
+		case <-time.After(time.Second):
+			t.Error("Expected messages to be submitted")
+		}
+	})
+	t.Run("push", func(t *testing.T) {
+		if err := messaging.Push(context.Background(), "U2", TextMessage("Hi")); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if sent["path"] != "/v2/bot/message/push" || sent["to"] != "U2" {
+			t.Errorf("Unexpected call %v", sent)
+		}
+		select {
+		case r := <-requests:
+			if !strings.Contains(r, `"user_id":"U2"`) || !strings.Contains(r, `"type":"agent"`) {
+				t.Errorf("Unexpected request %v", r)
+			}
+		case <-time.After(time.Second):
+			t.Error("Expected messages to be submitted")
+		}
+	})
+	t.Run("api error", func(t *testing.T) {
+		messaging := NewMessagingClient(client, "bad-token")
+		messaging.Endpoint = api.URL
+		if err := messaging.Push(context.Background(), "U2", TextMessage("Hi")); err == nil {
+			t.Error("Expected error, got nil")
+		}
+		select {
+		case r := <-requests:
+			t.Errorf("Unexpected request %v", r)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package line
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+// Handler wraps the handler of a LINE webhook. It verifies the request
+// signature and records all supported events before passing the request on.
+// All requests are rejected when ChannelSecret is empty
+type Handler struct {
+	Client        *chatbase.Client
+	ChannelSecret string
+	Next          http.Handler
+	// ErrorHandler is called when events cannot be recorded. Errors are
+	// discarded when it is nil
+	ErrorHandler func(error)
+}
+
+// NewHandler returns a new Handler using the given client, channel secret and handler
+func NewHandler(client *chatbase.Client, channelSecret string, next http.Handler) *Handler {
+	return &Handler{
+		Client:        client,
+		ChannelSecret: channelSecret,
+		Next:          next,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.ChannelSecret == "" {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	body, bodyErr := ioutil.ReadAll(r.Body)
+	if bodyErr != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	if err := VerifySignature(h.ChannelSecret, body, r.Header.Get("X-Line-Signature")); err != nil {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	var webhook Webhook
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		h.handleError(err)
+		h.Next.ServeHTTP(w, r)
+		return
+	}
+	messages := chatbase.Messages{}
+	events := chatbase.Events{}
+	for i := range webhook.Events {
+		message, event, err := Convert(h.Client, &webhook.Events[i])
+		if err != nil {
+			continue
+		}
+		if message != nil {
+			messages.Append(message)
+		}
+		if event != nil {
+			events.Append(event)
+		}
+	}
+	chatbase.SubmitAsync(h.ErrorHandler, &messages, &events)
+	h.Next.ServeHTTP(w, r)
+}
+
+func (h *Handler) handleError(err error) {
+	if err != nil && h.ErrorHandler != nil {
+		h.ErrorHandler(err)
+	}
+}
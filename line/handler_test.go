@@ -0,0 +1,93 @@
+package line
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (r roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r(req)
+}
+
+func captureRequests() chan string {
+	requests := make(chan string, 2)
+	chatbase.SetAPITransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		b, _ := ioutil.ReadAll(r.Body)
+		requests <- r.URL.Path + " " + string(b)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"all_succeeded":true,"status":200}`)),
+		}, nil
+	}))
+	return requests
+}
+
+func TestHandler(t *testing.T) {
+	defer chatbase.SetAPITransport(nil)
+	requests := captureRequests()
+
+	body := `{"destination":"x","events":[
+		{"type":"message","timestamp":1,"source":{"type":"user","userId":"U1"},"message":{"id":"1","type":"text","text":"Hello"}},
+		{"type":"follow","timestamp":2,"source":{"type":"user","userId":"U2"}},
+		{"type":"beacon","timestamp":3,"source":{"type":"user","userId":"U3"}}
+	]}`
+	t.Run("default", func(t *testing.T) {
+		called := false
+		h := NewHandler(chatbase.New("key"), "secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+			called = string(b) == body
+		}))
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("X-Line-Signature", sign("secret", body))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || !called {
+			t.Errorf("Unexpected response %v, called next: %v", rec.Code, called)
+		}
+		for _, expected := range []string{`"message":"Hello"`, `"intent":"follow"`} {
+			select {
+			case r := <-requests:
+				if !strings.Contains(r, expected) || strings.Contains(r, "U3") {
+					t.Errorf("Unexpected request %v", r)
+				}
+			case <-time.After(time.Second):
+				t.Error("Expected data to be submitted")
+			}
+		}
+	})
+	t.Run("bad signature", func(t *testing.T) {
+		h := NewHandler(chatbase.New("key"), "secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("Unexpected call of next handler")
+		}))
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("X-Line-Signature", sign("nope", body))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("Unexpected status %v", rec.Code)
+		}
+	})
+}
+
+func TestHandler_EmptyChannelSecret(t *testing.T) {
+	called := false
+	h := NewHandler(chatbase.New("key"), "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	body := `{"destination":"x","events":[]}`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("X-Line-Signature", sign("", body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden || called {
+		t.Errorf("Expected payload signed with empty secret to be rejected, got %v", rec.Code)
+	}
+}
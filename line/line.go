@@ -0,0 +1,224 @@
+/*
+Package line records LINE Messaging API conversations in Chatbase.
+
+Webhook events are verified using the channel secret and converted into user
+messages (message and postback events) or Chatbase events (follow and
+unfollow events). Replies and push messages sent using MessagingClient are
+recorded as agent messages, URI actions in templates are rewritten to
+Chatbase tracking links:
+
+	client := chatbase.New("MY-API-KEY")
+	http.Handle("/line", line.NewHandler(client, "CHANNEL-SECRET", webhookHandler))
+
+	messaging := line.NewMessagingClient(client, "CHANNEL-ACCESS-TOKEN")
+	err := messaging.Reply(ctx, event, line.TextMessage("Hello!"))
+*/
+package line
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+// ErrBadSignature is returned when the X-Line-Signature header does
+// not match the request body
+var ErrBadSignature = errors.New("request signature does not match")
+
+// ErrUnsupportedEvent is returned for events that cannot be recorded
+var ErrUnsupportedEvent = errors.New("event cannot be recorded")
+
+// Webhook is the payload sent to a webhook
+type Webhook struct {
+	Destination string  `json:"destination"`
+	Events      []Event `json:"events"`
+}
+
+// Event is a single webhook event
+type Event struct {
+	Type       string        `json:"type"`
+	ReplyToken string        `json:"replyToken,omitempty"`
+	Timestamp  int64         `json:"timestamp"`
+	Source     Source        `json:"source"`
+	Message    *EventMessage `json:"message,omitempty"`
+	Postback   *Postback     `json:"postback,omitempty"`
+}
+
+// Source describes where an event originated
+type Source struct {
+	Type    string `json:"type"`
+	UserID  string `json:"userId,omitempty"`
+	GroupID string `json:"groupId,omitempty"`
+	RoomID  string `json:"roomId,omitempty"`
+}
+
+// EventMessage is the message contained in a message event
+type EventMessage struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+// Postback is the data contained in a postback event
+type Postback struct {
+	Data string `json:"data"`
+}
+
+// Message is a message sent using the reply or push API
+type Message struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	AltText  string    `json:"altText,omitempty"`
+	Template *Template `json:"template,omitempty"`
+}
+
+// Template is the template of a template message
+type Template struct {
+	Type    string   `json:"type"`
+	Title   string   `json:"title,omitempty"`
+	Text    string   `json:"text,omitempty"`
+	Actions []Action `json:"actions,omitempty"`
+	Columns []Column `json:"columns,omitempty"`
+}
+
+// Column is a single column of a carousel template
+type Column struct {
+	Title             string   `json:"title,omitempty"`
+	Text              string   `json:"text"`
+	ThumbnailImageURL string   `json:"thumbnailImageUrl,omitempty"`
+	DefaultAction     *Action  `json:"defaultAction,omitempty"`
+	Actions           []Action `json:"actions"`
+}
+
+// Action is an action of a template
+type Action struct {
+	Type  string `json:"type"`
+	Label string `json:"label,omitempty"`
+	URI   string `json:"uri,omitempty"`
+	Data  string `json:"data,omitempty"`
+	Text  string `json:"text,omitempty"`
+}
+
+// TextMessage returns a new text message
+func TextMessage(text string) Message {
+	return Message{Type: "text", Text: text}
+}
+
+// VerifySignature checks the X-Line-Signature value of a request
+// against its body using the channel secret
+func VerifySignature(channelSecret string, body []byte, signature string) error {
+	expected, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return ErrBadSignature
+	}
+	mac := hmac.New(sha256.New, []byte(channelSecret))
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// SessionID returns the id of the group or room the event has been sent
+// in, or the id of the user for one-on-one chats
+func (s *Source) SessionID() string {
+	switch {
+	case s.GroupID != "":
+		return s.GroupID
+	case s.RoomID != "":
+		return s.RoomID
+	}
+	return s.UserID
+}
+
+// Convert maps a webhook event to either a user message (message and
+// postback events) or an event (follow and unfollow events). Other events
+// return ErrUnsupportedEvent
+func Convert(client *chatbase.Client, e *Event) (*chatbase.Message, *chatbase.Event, error) {
+	if e.Source.UserID == "" {
+		return nil, nil, ErrUnsupportedEvent
+	}
+	switch e.Type {
+	case "message":
+		m := client.UserMessage(e.Source.UserID, chatbase.PlatformLine).
+			SetTimeStamp(e.Timestamp).
+			SetSessionID(e.Source.SessionID())
+		if e.Message != nil {
+			m.SetMessage(e.Message.Text)
+		}
+		return m, nil, nil
+	case "postback":
+		m := client.UserMessage(e.Source.UserID, chatbase.PlatformLine).
+			SetTimeStamp(e.Timestamp).
+			SetSessionID(e.Source.SessionID())
+		if e.Postback != nil {
+			m.SetMessage(e.Postback.Data)
+		}
+		return m, nil, nil
+	case "follow", "unfollow":
+		ev := client.Event(e.Source.UserID, e.Type).
+			SetPlatform(chatbase.PlatformLine).
+			SetTimeStamp(e.Timestamp)
+		return nil, ev, nil
+	}
+	return nil, nil, ErrUnsupportedEvent
+}
+
+// AgentMessage converts a sent message into an agent message addressed to
+// the given user. Template messages are recorded using their alternative text
+func AgentMessage(client *chatbase.Client, userID, sessionID string, m *Message) *chatbase.Message {
+	text := m.Text
+	if text == "" {
+		text = m.AltText
+	}
+	return client.AgentMessage(userID, chatbase.PlatformLine).
+		SetMessage(text).
+		SetSessionID(sessionID)
+}
+
+// TrackLinks rewrites the URIs of all URI actions in the message's template
+// to Chatbase tracking links
+func TrackLinks(client *chatbase.Client, m *Message) error {
+	if m.Template == nil {
+		return nil
+	}
+	if err := trackActions(client, m.Template.Actions); err != nil {
+		return err
+	}
+	for i := range m.Template.Columns {
+		column := &m.Template.Columns[i]
+		if column.DefaultAction != nil {
+			if err := trackAction(client, column.DefaultAction); err != nil {
+				return err
+			}
+		}
+		if err := trackActions(client, column.Actions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func trackActions(client *chatbase.Client, actions []Action) error {
+	for i := range actions {
+		if err := trackAction(client, &actions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func trackAction(client *chatbase.Client, a *Action) error {
+	if a.Type != "uri" || a.URI == "" {
+		return nil
+	}
+	href, err := client.Link(a.URI, chatbase.PlatformLine).Encode()
+	if err != nil {
+		return err
+	}
+	a.URI = href
+	return nil
+}
@@ -0,0 +1,129 @@
+package line
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	tests := []struct {
+		name      string
+		signature string
+		expected  error
+	}{
+		{"default", sign("secret", "body"), nil},
+		{"bad secret", sign("nope", "body"), ErrBadSignature},
+		{"bad encoding", "%%%", ErrBadSignature},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := VerifySignature("secret", []byte("body"), test.signature); err != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	client := chatbase.New("key")
+	tests := []struct {
+		name            string
+		input           string
+		expectError     bool
+		expectedMessage *chatbase.Message
+		expectedEvent   *chatbase.Event
+	}{
+		{
+			"text message",
+			`{"type":"message","replyToken":"r","timestamp":1462629479859,"source":{"type":"user","userId":"U1"},"message":{"id":"1","type":"text","text":"Hello"}}`,
+			false,
+			&chatbase.Message{APIKey: "key", Type: chatbase.UserType, UserID: "U1", TimeStamp: 1462629479859, Platform: "Line", Message: "Hello", SessionID: "U1"},
+			nil,
+		},
+		{
+			"group postback",
+			`{"type":"postback","replyToken":"r","timestamp":1462629479859,"source":{"type":"group","userId":"U1","groupId":"G1"},"postback":{"data":"action=buy"}}`,
+			false,
+			&chatbase.Message{APIKey: "key", Type: chatbase.UserType, UserID: "U1", TimeStamp: 1462629479859, Platform: "Line", Message: "action=buy", SessionID: "G1"},
+			nil,
+		},
+		{
+			"follow",
+			`{"type":"follow","replyToken":"r","timestamp":1462629479859,"source":{"type":"user","userId":"U1"}}`,
+			false,
+			nil,
+			&chatbase.Event{APIKey: "key", UserID: "U1", Intent: "follow", TimeStamp: 1462629479859, Platform: "Line"},
+		},
+		{
+			"unfollow",
+			`{"type":"unfollow","timestamp":1462629479859,"source":{"type":"user","userId":"U1"}}`,
+			false,
+			nil,
+			&chatbase.Event{APIKey: "key", UserID: "U1", Intent: "unfollow", TimeStamp: 1462629479859, Platform: "Line"},
+		},
+		{"unsupported", `{"type":"beacon","timestamp":1,"source":{"type":"user","userId":"U1"}}`, true, nil, nil},
+		{"no user", `{"type":"message","timestamp":1,"source":{"type":"room","roomId":"R1"}}`, true, nil, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var e Event
+			if err := json.Unmarshal([]byte(test.input), &e); err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			message, event, err := Convert(client, &e)
+			if test.expectError != (err != nil) {
+				t.Errorf("Unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(test.expectedMessage, message) {
+				t.Errorf("Expected %#v, got %#v", test.expectedMessage, message)
+			}
+			if !reflect.DeepEqual(test.expectedEvent, event) {
+				t.Errorf("Expected %#v, got %#v", test.expectedEvent, event)
+			}
+		})
+	}
+}
+
+func TestTrackLinks(t *testing.T) {
+	client := chatbase.New("key")
+	m := Message{
+		Type:    "template",
+		AltText: "Choose",
+		Template: &Template{
+			Type:    "buttons",
+			Actions: []Action{{Type: "uri", URI: "https://example.net"}, {Type: "postback", Data: "x"}},
+			Columns: []Column{{DefaultAction: &Action{Type: "uri", URI: "https://example.net/a"}, Actions: []Action{{Type: "uri", URI: "https://example.net/b"}}}},
+		},
+	}
+	if err := TrackLinks(client, &m); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := func(uri string) string {
+		href, _ := client.Link(uri, chatbase.PlatformLine).Encode()
+		return href
+	}
+	if u := m.Template.Actions[0].URI; u != expected("https://example.net") {
+		t.Errorf("Unexpected URI %v", u)
+	}
+	if d := m.Template.Actions[1]; d.URI != "" || d.Data != "x" {
+		t.Errorf("Unexpected action %v", d)
+	}
+	if u := m.Template.Columns[0].DefaultAction.URI; u != expected("https://example.net/a") {
+		t.Errorf("Unexpected URI %v", u)
+	}
+	if u := m.Template.Columns[0].Actions[0].URI; u != expected("https://example.net/b") {
+		t.Errorf("Unexpected URI %v", u)
+	}
+}
@@ -16,6 +16,8 @@ type Link struct {
 	URL      string `json:"url"`
 	Platform string `json:"platform"`
 	Version  string `json:"version,omitempty"`
+
+	config *config
 }
 
 // LinkResponse contains the response to submitting a link
@@ -32,16 +34,14 @@ func (l *Link) SetVersion(v string) *Link {
 
 // Submit tries to send the link to Chatbase
 func (l *Link) Submit() (*LinkResponse, error) {
-	return newLinkResponse(func() (io.ReadCloser, error) {
-		return apiPost(clickEndpoint, l)
-	})
+	return l.submit(context.Background())
 }
 
 // SubmitWithContext tries to send the link to Chatbase
 // while considering the given context's deadline
 func (l *Link) SubmitWithContext(ctx context.Context) (*LinkResponse, error) {
 	data, err := resultWithContext(ctx, func() (interface{}, error) {
-		return l.Submit()
+		return l.submit(ctx)
 	})
 	if err != nil {
 		return nil, err
@@ -52,6 +52,12 @@ func (l *Link) SubmitWithContext(ctx context.Context) (*LinkResponse, error) {
 	return nil, errBadData
 }
 
+func (l *Link) submit(ctx context.Context) (*LinkResponse, error) {
+	return newLinkResponse(func() (io.ReadCloser, error) {
+		return apiPost(ctx, l.config, clickEndpoint, l)
+	})
+}
+
 // Encode turns the link object into a URL
 func (l *Link) Encode() (string, error) {
 	params := map[string]string{
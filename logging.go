@@ -0,0 +1,90 @@
+package chatbase
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"strings"
+)
+
+// redacted replaces API keys in logged URLs
+const redacted = "REDACTED"
+
+// WithLogger logs all submissions of payloads created by the client using
+// the given logger. Successful requests are logged at debug level, rejected
+// items and dropped data as warnings and failed requests as errors.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *config) {
+		c.logger = l
+	}
+}
+
+func (c *config) logCall(ctx context.Context, info *call, err error) {
+	if c.logger == nil {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("endpoint", info.kind),
+		slog.String("method", info.method),
+		slog.String("url", redactURL(info.endpoint)),
+		slog.Int("items", info.items),
+	}
+	if info.statusCode != 0 {
+		attrs = append(attrs, slog.Int("status_code", info.statusCode))
+	}
+	if info.sent {
+		attrs = append(attrs, slog.Duration("duration", info.duration))
+	}
+
+	switch {
+	case err != nil:
+		attrs = append(attrs, slog.String("error", redactError(err)))
+		c.logger.LogAttrs(ctx, slog.LevelError, "chatbase request failed", attrs...)
+	case len(info.failures) > 0:
+		attrs = append(attrs,
+			slog.Int("failed_items", len(info.failures)),
+			slog.Any("reasons", info.failures),
+		)
+		c.logger.LogAttrs(ctx, slog.LevelWarn, "chatbase rejected items of batch", attrs...)
+	case info.status != nil && !info.status.OK():
+		attrs = append(attrs, slog.String("reason", info.reason))
+		c.logger.LogAttrs(ctx, slog.LevelWarn, "chatbase rejected submission", attrs...)
+	default:
+		c.logger.LogAttrs(ctx, slog.LevelDebug, "chatbase request", attrs...)
+	}
+}
+
+func (c *config) logDropped(pipeline, reason string) {
+	if c.logger == nil {
+		return
+	}
+	c.logger.LogAttrs(context.Background(), slog.LevelWarn, "chatbase dropped data",
+		slog.String("pipeline", pipeline),
+		slog.String("reason", reason),
+	)
+}
+
+// redactURL replaces the value of the api_key parameter that is added
+// to some endpoints by augmentURL
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	q := u.Query()
+	if q.Get("api_key") == "" {
+		return raw
+	}
+	q.Set("api_key", redacted)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// redactError removes API keys from errors returned by the http.Client,
+// which contain the requested URL
+func redactError(err error) string {
+	if urlErr, ok := err.(*url.Error); ok {
+		return strings.Replace(err.Error(), urlErr.URL, redactURL(urlErr.URL), 1)
+	}
+	return err.Error()
+}
@@ -0,0 +1,198 @@
+package chatbase
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func captureLogs() (*slog.Logger, func() []map[string]interface{}) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	return logger, func() []map[string]interface{} {
+		var records []map[string]interface{}
+		dec := json.NewDecoder(&buf)
+		for dec.More() {
+			var record map[string]interface{}
+			dec.Decode(&record)
+			records = append(records, record)
+		}
+		return records
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	oldMessage, oldMessages, oldFacebook := messageEndpoint, messagesEndpoint, facebookMessageEndpoint
+	defer func() { messageEndpoint, messagesEndpoint, facebookMessageEndpoint = oldMessage, oldMessages, oldFacebook }()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rejected":
+			w.Write([]byte(`{"status":400,"reason":"missing platform"}`))
+		case "/partial":
+			w.Write([]byte(`{"all_succeeded":false,"status":200,"responses":[{"status":"success"},{"status":"failure","reason":"bad"}]}`))
+		case "/fail":
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		default:
+			w.Write([]byte(`{"message_id":"123","status":200}`))
+		}
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name            string
+		endpoints       func()
+		submit          func(c *Client)
+		expectedLevel   string
+		expectedMessage string
+		expectedAttrs   map[string]interface{}
+	}{
+		{
+			"success",
+			func() { messageEndpoint = ts.URL },
+			func(c *Client) { c.UserMessage("user", PlatformWeb).Submit() },
+			"DEBUG",
+			"chatbase request",
+			map[string]interface{}{"endpoint": "message", "status_code": 200.0, "items": 1.0},
+		},
+		{
+			"rejected",
+			func() { messageEndpoint = ts.URL + "/rejected" },
+			func(c *Client) { c.UserMessage("user", PlatformWeb).Submit() },
+			"WARN",
+			"chatbase rejected submission",
+			map[string]interface{}{"reason": "missing platform"},
+		},
+		{
+			"partial batch failure",
+			func() { messagesEndpoint = ts.URL + "/partial" },
+			func(c *Client) {
+				messages := Messages{}
+				messages.Append(c.UserMessage("user", PlatformWeb), c.AgentMessage("user", PlatformWeb))
+				messages.Submit()
+			},
+			"WARN",
+			"chatbase rejected items of batch",
+			map[string]interface{}{"failed_items": 1.0, "items": 2.0},
+		},
+		{
+			"server error",
+			func() { messageEndpoint = ts.URL + "/fail" },
+			func(c *Client) { c.UserMessage("user", PlatformWeb).Submit() },
+			"ERROR",
+			"chatbase request failed",
+			map[string]interface{}{"status_code": 500.0, "error": "request failed with status 500"},
+		},
+		{
+			"redacted url",
+			func() { facebookMessageEndpoint = ts.URL + "/facebook" },
+			func(c *Client) { c.FacebookMessage(map[string]string{}).Submit() },
+			"DEBUG",
+			"chatbase request",
+			map[string]interface{}{"url": ts.URL + "/facebook?api_key=REDACTED"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logger, records := captureLogs()
+			test.endpoints()
+			test.submit(New("secret-key", WithLogger(logger)))
+			logs := records()
+			if len(logs) != 1 {
+				t.Fatalf("Expected 1 record, got %v", logs)
+			}
+			record := logs[0]
+			if record["level"] != test.expectedLevel || record["msg"] != test.expectedMessage {
+				t.Errorf("Unexpected record %v", record)
+			}
+			for key, value := range test.expectedAttrs {
+				if record[key] != value {
+					t.Errorf("Expected %v to be %v, got %v", key, value, record[key])
+				}
+			}
+		})
+	}
+}
+
+func TestWithLogger_NotSent(t *testing.T) {
+	logger, records := captureLogs()
+	New("key", WithLogger(logger)).FacebookMessage(func() {}).Submit()
+	logs := records()
+	if len(logs) != 1 || logs[0]["msg"] != "chatbase request failed" {
+		t.Fatalf("Expected failed request to be logged, got %v", logs)
+	}
+	if _, ok := logs[0]["duration"]; ok {
+		t.Errorf("Expected no duration for request that was not sent, got %v", logs[0])
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			"url error",
+			&url.Error{Op: "Post", URL: "https://chatbase.com/api/facebook/message_received?api_key=secret-key", Err: errors.New("boom")},
+			`Post "https://chatbase.com/api/facebook/message_received?api_key=REDACTED": boom`,
+		},
+		{"other error", errors.New("boom"), "boom"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if s := redactError(test.err); s != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, s)
+			}
+			if strings.Contains(redactError(test.err), "secret-key") {
+				t.Error("Expected API key to be redacted")
+			}
+		})
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"api key", "https://chatbase.com/api/message/update?api_key=secret&message_id=1", "https://chatbase.com/api/message/update?api_key=REDACTED&message_id=1"},
+		{"no api key", "https://chatbase.com/api/message", "https://chatbase.com/api/message"},
+		{"invalid", "%%", "%%"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if s := redactURL(test.input); s != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, s)
+			}
+		})
+	}
+}
+
+func TestWithLogger_Dropped(t *testing.T) {
+	sendAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"recipient_id":"1","message_id":"mid"}`))
+	}))
+	defer sendAPI.Close()
+
+	logger, records := captureLogs()
+	transport := New("key", WithLogger(logger)).FacebookTransport(nil)
+	transport.Close()
+	res, err := (&http.Client{Transport: transport}).Post(sendAPI.URL+"/me/messages", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	res.Body.Close()
+
+	logs := records()
+	if len(logs) != 1 || logs[0]["level"] != "WARN" || logs[0]["pipeline"] != facebookTransportPipeline {
+		t.Errorf("Unexpected records %v", logs)
+	}
+}
@@ -58,6 +58,8 @@ type Message struct {
 	Feedback   bool        `json:"feedback,omitempty"`
 	Version    string      `json:"version,omitempty"`
 	SessionID  string      `json:"session_id,omitempty"`
+
+	config *config
 }
 
 // SetMessage adds an optional "message" value to a message
@@ -104,16 +106,14 @@ func (m *Message) SetTimeStamp(t int64) *Message {
 
 // Submit tries to deliver the message to Chatbase
 func (m *Message) Submit() (*MessageResponse, error) {
-	return newMessageResponse(func() (io.ReadCloser, error) {
-		return apiPost(messageEndpoint, m)
-	})
+	return m.submit(context.Background())
 }
 
 // SubmitWithContext tries to deliver the message to Chatbase
 // while considering the given context's deadline
 func (m *Message) SubmitWithContext(ctx context.Context) (*MessageResponse, error) {
 	data, err := resultWithContext(ctx, func() (interface{}, error) {
-		return m.Submit()
+		return m.submit(ctx)
 	})
 	if err != nil {
 		return nil, err
@@ -124,6 +124,12 @@ func (m *Message) SubmitWithContext(ctx context.Context) (*MessageResponse, erro
 	return nil, errBadData
 }
 
+func (m *Message) submit(ctx context.Context) (*MessageResponse, error) {
+	return newMessageResponse(func() (io.ReadCloser, error) {
+		return apiPost(ctx, m.config, messageEndpoint, m)
+	})
+}
+
 // MessageResponse describes a Chatbase response to the submission of
 // a single message. It is also used to represent the result of an item
 // of a collection of messages that have been submitted.
@@ -131,6 +137,10 @@ type MessageResponse struct {
 	MessageID MessageID `json:"message_id"`
 	Status    Status    `json:"status"`
 	Reason    string    `json:"reason,omitempty"`
+	// Withheld is set to the pipeline that kept the message from being
+	// submitted, i.e. "consent" or "sampling". Withheld messages are
+	// reported with a successful status and without message id
+	Withheld string `json:"withheld,omitempty"`
 }
 
 // Messages is a collection of Message
@@ -146,16 +156,14 @@ func (m Messages) MarshalJSON() ([]byte, error) {
 
 // Submit tries to deliver the set of messages to Chatbase
 func (m *Messages) Submit() (*MessagesResponse, error) {
-	return newMessagesResponse(func() (io.ReadCloser, error) {
-		return apiPost(messagesEndpoint, m)
-	})
+	return m.submit(context.Background())
 }
 
 // SubmitWithContext tries to deliver the set of messages to Chatbase
 // while considering the given context's deadline
 func (m *Messages) SubmitWithContext(ctx context.Context) (*MessagesResponse, error) {
 	data, err := resultWithContext(ctx, func() (interface{}, error) {
-		return m.Submit()
+		return m.submit(ctx)
 	})
 	if err != nil {
 		return nil, err
@@ -166,6 +174,23 @@ func (m *Messages) SubmitWithContext(ctx context.Context) (*MessagesResponse, er
 	return nil, errBadData
 }
 
+func (m *Messages) submit(ctx context.Context) (*MessagesResponse, error) {
+	cfg, err := m.config()
+	if err != nil {
+		return nil, err
+	}
+	return newMessagesResponse(func() (io.ReadCloser, error) {
+		return apiPost(ctx, cfg, messagesEndpoint, m)
+	})
+}
+
+// config returns the configuration shared by the collection's messages
+func (m *Messages) config() (*config, error) {
+	return collectionConfig(len(*m), func(i int) *config {
+		return (*m)[i].config
+	})
+}
+
 // Append adds messages to the the collection
 func (m *Messages) Append(addition ...*Message) *Messages {
 	for _, a := range addition {
@@ -0,0 +1,138 @@
+package chatbase
+
+import (
+	"encoding/json"
+)
+
+// MessengerUser identifies the sender or recipient of a Messenger message
+type MessengerUser struct {
+	ID      string `json:"id,omitempty"`
+	UserRef string `json:"user_ref,omitempty"`
+}
+
+// MessengerEvent is a single messaging event delivered to a Messenger webhook
+type MessengerEvent struct {
+	Sender    MessengerUser      `json:"sender"`
+	Recipient MessengerUser      `json:"recipient"`
+	Timestamp int64              `json:"timestamp,omitempty"`
+	Message   *MessengerMessage  `json:"message,omitempty"`
+	Postback  *MessengerPostback `json:"postback,omitempty"`
+	Referral  *MessengerReferral `json:"referral,omitempty"`
+}
+
+// MessengerMessage is the content of a Messenger message. It is used both for
+// messages received by a webhook and messages sent using the Send API
+type MessengerMessage struct {
+	MID          string                `json:"mid,omitempty"`
+	Text         string                `json:"text,omitempty"`
+	IsEcho       bool                  `json:"is_echo,omitempty"`
+	Metadata     string                `json:"metadata,omitempty"`
+	QuickReply   *MessengerQuickReply  `json:"quick_reply,omitempty"`
+	QuickReplies []MessengerQuickReply `json:"quick_replies,omitempty"`
+	Attachment   *MessengerAttachment  `json:"attachment,omitempty"`
+	Attachments  []MessengerAttachment `json:"attachments,omitempty"`
+}
+
+// MessengerQuickReply is a quick reply offered in a sent message or the
+// quick reply chosen by the user in a received message
+type MessengerQuickReply struct {
+	ContentType string `json:"content_type,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Payload     string `json:"payload,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+}
+
+// MessengerAttachment is a media or template attachment of a message
+type MessengerAttachment struct {
+	Type    string                     `json:"type"`
+	Payload MessengerAttachmentPayload `json:"payload"`
+}
+
+// MessengerAttachmentPayload contains the data of an attachment. Media
+// attachments use the URL, templates use the template specific fields
+type MessengerAttachmentPayload struct {
+	URL          string             `json:"url,omitempty"`
+	IsReusable   bool               `json:"is_reusable,omitempty"`
+	AttachmentID string             `json:"attachment_id,omitempty"`
+	TemplateType string             `json:"template_type,omitempty"`
+	Text         string             `json:"text,omitempty"`
+	Buttons      []MessengerButton  `json:"buttons,omitempty"`
+	Elements     []MessengerElement `json:"elements,omitempty"`
+}
+
+// MessengerButton is a button used in templates
+type MessengerButton struct {
+	Type    string `json:"type"`
+	Title   string `json:"title,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Payload string `json:"payload,omitempty"`
+}
+
+// MessengerElement is a single element of a generic or list template
+type MessengerElement struct {
+	Title         string            `json:"title"`
+	Subtitle      string            `json:"subtitle,omitempty"`
+	ImageURL      string            `json:"image_url,omitempty"`
+	DefaultAction *MessengerButton  `json:"default_action,omitempty"`
+	Buttons       []MessengerButton `json:"buttons,omitempty"`
+}
+
+// MessengerPostback is sent when a user taps a postback button
+type MessengerPostback struct {
+	Title    string             `json:"title,omitempty"`
+	Payload  string             `json:"payload,omitempty"`
+	Referral *MessengerReferral `json:"referral,omitempty"`
+}
+
+// MessengerReferral describes how a user entered a conversation
+type MessengerReferral struct {
+	Ref    string `json:"ref,omitempty"`
+	Source string `json:"source,omitempty"`
+	Type   string `json:"type,omitempty"`
+	AdID   string `json:"ad_id,omitempty"`
+}
+
+// MessengerSendRequest is the body of a call to the Send API
+type MessengerSendRequest struct {
+	MessagingType    string            `json:"messaging_type,omitempty"`
+	Recipient        MessengerUser     `json:"recipient"`
+	Message          *MessengerMessage `json:"message,omitempty"`
+	SenderAction     string            `json:"sender_action,omitempty"`
+	NotificationType string            `json:"notification_type,omitempty"`
+	Tag              string            `json:"tag,omitempty"`
+}
+
+// MessengerSendResponse is the response to a call to the Send API
+type MessengerSendResponse struct {
+	RecipientID  string          `json:"recipient_id,omitempty"`
+	MessageID    string          `json:"message_id,omitempty"`
+	AttachmentID string          `json:"attachment_id,omitempty"`
+	Error        *MessengerError `json:"error,omitempty"`
+}
+
+// MessengerError describes an error returned by the Send API
+type MessengerError struct {
+	Message      string `json:"message"`
+	Type         string `json:"type,omitempty"`
+	Code         int    `json:"code,omitempty"`
+	ErrorSubcode int    `json:"error_subcode,omitempty"`
+	FBTraceID    string `json:"fbtrace_id,omitempty"`
+}
+
+// Events decodes the entry's messaging events into typed values
+func (e *FacebookWebhookEntry) Events() ([]MessengerEvent, error) {
+	events := make([]MessengerEvent, len(e.Messaging))
+	for i, raw := range e.Messaging {
+		if err := json.Unmarshal(raw, &events[i]); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// typedFacebookMessage is used for serializing messages with a typed payload
+// without having to merge the metadata using an intermediate map
+type typedFacebookMessage struct {
+	*MessengerEvent
+	Fields *FacebookFields `json:"chatbase_fields,omitempty"`
+}
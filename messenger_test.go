@@ -0,0 +1,125 @@
+package chatbase
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestMessengerEvent_MarshalJSON(t *testing.T) {
+	b, err := ioutil.ReadFile("testdata/facebook_single_payload.json")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	var event MessengerEvent
+	if err := json.Unmarshal(b, &event); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if event.Sender.ID != "15208331041" || event.Message.Text != "hey chatbot!" {
+		t.Fatalf("Unexpected event %#v", event)
+	}
+	var untyped map[string]interface{}
+	if err := json.Unmarshal(b, &untyped); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	fields := &FacebookFields{Intent: "greeting", Version: "1.0.0"}
+	tests := []struct {
+		name    string
+		payload interface{}
+	}{
+		{"pointer", &event},
+		{"value", event},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			typed, typedErr := json.Marshal(FacebookMessage{Payload: test.payload, Fields: fields})
+			if typedErr != nil {
+				t.Fatalf("Unexpected error %v", typedErr)
+			}
+			expected, expectedErr := json.Marshal(FacebookMessage{Payload: untyped, Fields: fields})
+			if expectedErr != nil {
+				t.Fatalf("Unexpected error %v", expectedErr)
+			}
+			var typedResult, expectedResult map[string]interface{}
+			json.Unmarshal(typed, &typedResult)
+			json.Unmarshal(expected, &expectedResult)
+			if !reflect.DeepEqual(expectedResult, typedResult) {
+				t.Errorf("Expected %s, got %s", expected, typed)
+			}
+		})
+	}
+	t.Run("nil pointer", func(t *testing.T) {
+		var nilEvent *MessengerEvent
+		b, err := json.Marshal(FacebookMessage{Payload: nilEvent})
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if s := string(b); s != "null" {
+			t.Errorf("Unexpected result %v", s)
+		}
+	})
+}
+
+func TestMessengerSendRequest_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    interface{}
+		fixture  string
+		expected interface{}
+	}{
+		{"request", &MessengerSendRequest{}, "testdata/facebook_single_request.json", &MessengerSendRequest{
+			Recipient: MessengerUser{ID: "15208331041"},
+			Message:   &MessengerMessage{Text: "hello, world!"},
+		}},
+		{"response", &MessengerSendResponse{}, "testdata/facebook_single_response.json", &MessengerSendResponse{
+			RecipientID: "15208331041",
+			MessageID:   "mid.1456970487936:c34767dfe57ee6e339",
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := ioutil.ReadFile(test.fixture)
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if err := json.Unmarshal(b, test.input); err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(test.expected, test.input) {
+				t.Errorf("Expected %#v, got %#v", test.expected, test.input)
+			}
+		})
+	}
+}
+
+func TestFacebookWebhookEntry_Events(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		entry := FacebookWebhookEntry{Messaging: []json.RawMessage{
+			json.RawMessage(`{"sender":{"id":"u1"},"postback":{"title":"Start","payload":"GET_STARTED","referral":{"ref":"ad","source":"ADS","type":"OPEN_THREAD"}}}`),
+			json.RawMessage(`{"sender":{"id":"u1"},"message":{"mid":"m1","quick_reply":{"payload":"YES"},"attachments":[{"type":"image","payload":{"url":"https://example.net/cat.png"}}]}}`),
+		}}
+		events, err := entry.Events()
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		expected := []MessengerEvent{
+			{Sender: MessengerUser{ID: "u1"}, Postback: &MessengerPostback{Title: "Start", Payload: "GET_STARTED", Referral: &MessengerReferral{Ref: "ad", Source: "ADS", Type: "OPEN_THREAD"}}},
+			{Sender: MessengerUser{ID: "u1"}, Message: &MessengerMessage{
+				MID:         "m1",
+				QuickReply:  &MessengerQuickReply{Payload: "YES"},
+				Attachments: []MessengerAttachment{{Type: "image", Payload: MessengerAttachmentPayload{URL: "https://example.net/cat.png"}}},
+			}},
+		}
+		if !reflect.DeepEqual(expected, events) {
+			t.Errorf("Expected %#v, got %#v", expected, events)
+		}
+	})
+	t.Run("bad event", func(t *testing.T) {
+		entry := FacebookWebhookEntry{Messaging: []json.RawMessage{json.RawMessage(`"zalgo"`)}}
+		if _, err := entry.Events(); err == nil {
+			t.Error("Expected error, got nil")
+		}
+	})
+}
@@ -0,0 +1,138 @@
+/*
+Package metrics records Prometheus metrics about submissions to Chatbase.
+
+Metrics are collected by an interceptor wrapping all API calls of a client
+and by a pipeline observer counting queued and dropped items:
+
+	m, err := metrics.New(prometheus.DefaultRegisterer)
+	if err != nil {
+		// handle error
+	}
+	client := chatbase.New("MY-API-KEY",
+		chatbase.WithInterceptors(m.Intercept),
+		chatbase.WithPipelineObserver(m),
+	)
+*/
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics collects Prometheus metrics about the health of submissions
+// to Chatbase. A single instance can be shared by multiple clients.
+type Metrics struct {
+	requests     *prometheus.CounterVec
+	latency      *prometheus.HistogramVec
+	itemFailures *prometheus.CounterVec
+	queueDepth   *prometheus.GaugeVec
+	dropped      *prometheus.CounterVec
+}
+
+// New creates the collectors used for recording metrics and
+// registers them on the given registerer
+func New(registerer prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "chatbase",
+			Name:      "requests_total",
+			Help:      "Number of requests made to the Chatbase API by endpoint and HTTP status class.",
+		}, []string{"endpoint", "status_class"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "chatbase",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of requests made to the Chatbase API by endpoint.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"endpoint"}),
+		itemFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "chatbase",
+			Name:      "item_failures_total",
+			Help:      "Number of items in batch submissions that have been rejected by Chatbase.",
+		}, []string{"endpoint"}),
+		queueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "chatbase",
+			Name:      "queue_depth",
+			Help:      "Number of items waiting for submission by pipeline.",
+		}, []string{"pipeline"}),
+		dropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "chatbase",
+			Name:      "dropped_items_total",
+			Help:      "Number of items that have been dropped before submission by pipeline.",
+		}, []string{"pipeline"}),
+	}
+	for _, collector := range []prometheus.Collector{m.requests, m.latency, m.itemFailures, m.queueDepth, m.dropped} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Intercept is a chatbase.Interceptor recording the outcome and latency of
+// API calls. Calls that have not been sent, e.g. because another interceptor
+// answered them, are not recorded
+func (m *Metrics) Intercept(ctx context.Context, req *chatbase.APIRequest, next chatbase.APICall) (*chatbase.APIResponse, error) {
+	start := time.Now()
+	res, err := next(ctx, req)
+	if !chatbase.Sent(ctx) {
+		return res, err
+	}
+	statusCode := 0
+	if res != nil {
+		statusCode = res.StatusCode
+	}
+	m.requests.WithLabelValues(req.Kind, statusClass(statusCode)).Inc()
+	m.latency.WithLabelValues(req.Kind).Observe(time.Since(start).Seconds())
+	if failures := itemFailures(res); failures > 0 {
+		m.itemFailures.WithLabelValues(req.Kind).Add(float64(failures))
+	}
+	return res, err
+}
+
+// Queued implements chatbase.PipelineObserver
+func (m *Metrics) Queued(pipeline string, delta int) {
+	m.queueDepth.WithLabelValues(pipeline).Add(float64(delta))
+}
+
+// Dropped implements chatbase.PipelineObserver
+func (m *Metrics) Dropped(pipeline string, count int) {
+	m.dropped.WithLabelValues(pipeline).Add(float64(count))
+}
+
+// itemFailures returns the number of items of a batch submission
+// that have been rejected by Chatbase
+func itemFailures(res *chatbase.APIResponse) int {
+	if res == nil {
+		return 0
+	}
+	var body struct {
+		Responses []struct {
+			Status chatbase.Status `json:"status"`
+		} `json:"responses"`
+	}
+	if json.Unmarshal(res.Body, &body) != nil {
+		return 0
+	}
+	failures := 0
+	for _, item := range body.Responses {
+		if !item.Status.OK() {
+			failures++
+		}
+	}
+	return failures
+}
+
+// statusClass returns the class of an HTTP status code, a status
+// code of zero means the request did not receive a response
+func statusClass(statusCode int) string {
+	if statusCode == 0 {
+		return "error"
+	}
+	return strconv.Itoa(statusCode/100) + "xx"
+}
@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestNew(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	if _, err := New(registry); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if _, err := New(registry); err == nil {
+		t.Error("Expected error when registering metrics twice")
+	}
+}
+
+func TestMetrics_Intercept(t *testing.T) {
+	defer chatbase.SetAPITransport(nil)
+	chatbase.SetAPITransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/insert_batch") {
+			return &http.Response{StatusCode: http.StatusInternalServerError, Body: ioutil.NopCloser(strings.NewReader("internal server error"))}, nil
+		}
+		body := `{"all_succeeded":false,"status":200,"responses":[{"status":"success"},{"status":"failure","reason":"bad"}]}`
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+	}))
+
+	m, err := New(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	client := chatbase.New("key", chatbase.WithInterceptors(m.Intercept))
+
+	messages := chatbase.Messages{}
+	messages.Append(client.UserMessage("user", chatbase.PlatformWeb), client.AgentMessage("user", chatbase.PlatformWeb))
+	if _, err := messages.Submit(); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	events := chatbase.Events{}
+	events.Append(client.Event("user", "intent"))
+	events.Submit()
+
+	tests := []struct {
+		name      string
+		collector prometheus.Collector
+		expected  float64
+	}{
+		{"messages ok", m.requests.WithLabelValues("messages", "2xx"), 1},
+		{"events failed", m.requests.WithLabelValues("events", "5xx"), 1},
+		{"item failures", m.itemFailures.WithLabelValues("messages"), 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if v := testutil.ToFloat64(test.collector); v != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, v)
+			}
+		})
+	}
+	if n := testutil.CollectAndCount(m.latency); n != 2 {
+		t.Errorf("Expected latency for 2 endpoints, got %d", n)
+	}
+}
+
+func TestMetrics_NotSent(t *testing.T) {
+	m, err := New(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	cached := func(ctx context.Context, req *chatbase.APIRequest, next chatbase.APICall) (*chatbase.APIResponse, error) {
+		return &chatbase.APIResponse{StatusCode: http.StatusOK, Body: []byte(`{"status":200}`)}, nil
+	}
+	chatbase.New("key", chatbase.WithInterceptors(m.Intercept, cached)).Event("user", "intent").Submit()
+	chatbase.New("key", chatbase.WithInterceptors(m.Intercept)).FacebookMessage(func() {}).Submit()
+	if n := testutil.CollectAndCount(m.requests); n != 0 {
+		t.Errorf("Expected requests that were not sent to be ignored, got %d series", n)
+	}
+	if n := testutil.CollectAndCount(m.latency); n != 0 {
+		t.Errorf("Expected no latency to be recorded, got %d series", n)
+	}
+}
+
+func TestMetrics_PipelineObserver(t *testing.T) {
+	m, err := New(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	var observer chatbase.PipelineObserver = m
+	observer.Queued("facebook_transport", 2)
+	observer.Queued("facebook_transport", -1)
+	observer.Dropped("consent", 3)
+	if v := testutil.ToFloat64(m.queueDepth.WithLabelValues("facebook_transport")); v != 1 {
+		t.Errorf("Expected queue depth of 1, got %v", v)
+	}
+	if v := testutil.ToFloat64(m.dropped.WithLabelValues("consent")); v != 3 {
+		t.Errorf("Expected 3 dropped items, got %v", v)
+	}
+}
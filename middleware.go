@@ -0,0 +1,95 @@
+package chatbase
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// Extractor extracts the user id, the message text and the platform
+// from an inbound bot webhook request. The request body can be read
+// freely as it is restored before the request is passed on.
+type Extractor func(r *http.Request) (userID, text, platform string, err error)
+
+// Middleware logs the messages of an HTTP based bot. The inbound request
+// is recorded as a user message, the response body written by the wrapped
+// handler is recorded as the agent's reply.
+type Middleware struct {
+	Client    *Client
+	Extractor Extractor
+	// ErrorHandler is called when extracting or submitting the messages
+	// fails. Errors are discarded when it is nil
+	ErrorHandler func(error)
+	// MaxRecordedBody is the maximum number of bytes of the response body
+	// that is recorded, defaults to DefaultMaxRecordedBody. The response
+	// itself is never truncated
+	MaxRecordedBody int
+}
+
+// Middleware returns a new Middleware using the client and the given extractor
+func (c *Client) Middleware(extract Extractor) *Middleware {
+	return &Middleware{
+		Client:    c,
+		Extractor: extract,
+	}
+}
+
+// Wrap returns a handler that calls next and submits the messages
+// asynchronously once it has returned, so the response is never delayed
+// or altered by the submission
+func (m *Middleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userMessage, extractErr := m.userMessage(r)
+		if extractErr != nil {
+			m.handleError(extractErr)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := NewResponseRecorder(w, m.MaxRecordedBody)
+		next.ServeHTTP(recorder, r)
+
+		messages := Messages{}
+		messages.Append(userMessage)
+		if body := recorder.Body(); len(body) > 0 {
+			agentMessage := m.Client.AgentMessage(userMessage.UserID, userMessage.Platform)
+			agentMessage.SetMessage(string(body))
+			messages.Append(agentMessage)
+		}
+		SubmitAsync(m.ErrorHandler, &messages)
+	})
+}
+
+func (m *Middleware) userMessage(r *http.Request) (*Message, error) {
+	var body []byte
+	if r.Body != nil {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			// the wrapped handler still receives the bytes that have
+			// been consumed, followed by the rest of the original body
+			r.Body = readCloser{io.MultiReader(bytes.NewReader(b), r.Body), r.Body}
+			return nil, err
+		}
+		r.Body.Close()
+		body = b
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	userID, text, platform, err := m.Extractor(r)
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	return m.Client.UserMessage(userID, platform).SetMessage(text), nil
+}
+
+func (m *Middleware) handleError(err error) {
+	if m.ErrorHandler != nil {
+		m.ErrorHandler(err)
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,171 @@
+package chatbase
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMiddleware_Wrap(t *testing.T) {
+	oldEndpoint := messagesEndpoint
+	defer func() { messagesEndpoint = oldEndpoint }()
+	submitted := make(chan Messages)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Messages Messages `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.Write([]byte(`{"all_succeeded":true,"status":200}`))
+		submitted <- payload.Messages
+	}))
+	defer ts.Close()
+	messagesEndpoint = ts.URL
+
+	extract := func(r *http.Request) (string, string, string, error) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return "", "", "", err
+		}
+		if len(b) == 0 {
+			return "", "", "", errors.New("empty body")
+		}
+		return r.URL.Query().Get("user"), string(b), PlatformWeb, nil
+	}
+
+	t.Run("default", func(t *testing.T) {
+		handler := New("key").Middleware(extract).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("you said: " + string(b)))
+		}))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/?user=abc", strings.NewReader("hello")))
+		if rec.Code != http.StatusCreated || rec.Body.String() != "you said: hello" {
+			t.Errorf("Unexpected response %v %v", rec.Code, rec.Body.String())
+		}
+
+		select {
+		case messages := <-submitted:
+			if len(messages) != 2 {
+				t.Fatalf("Unexpected messages %v", messages)
+			}
+			if m := messages[0]; m.Type != UserType || m.UserID != "abc" || m.Message != "hello" || m.Platform != PlatformWeb {
+				t.Errorf("Unexpected user message %v", m)
+			}
+			if m := messages[1]; m.Type != AgentType || m.UserID != "abc" || m.Message != "you said: hello" {
+				t.Errorf("Unexpected agent message %v", m)
+			}
+		case <-time.After(time.Second):
+			t.Error("Expected messages to be submitted")
+		}
+	})
+	t.Run("empty response", func(t *testing.T) {
+		handler := New("key").Middleware(extract).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/?user=abc", strings.NewReader("hello")))
+		select {
+		case messages := <-submitted:
+			if len(messages) != 1 || messages[0].Type != UserType {
+				t.Errorf("Unexpected messages %v", messages)
+			}
+		case <-time.After(time.Second):
+			t.Error("Expected messages to be submitted")
+		}
+	})
+	t.Run("extractor error", func(t *testing.T) {
+		errs := make(chan error, 1)
+		m := New("key").Middleware(extract)
+		m.ErrorHandler = func(err error) { errs <- err }
+		called := false
+		handler := m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+		if !called {
+			t.Error("Expected wrapped handler to be called")
+		}
+		select {
+		case err := <-errs:
+			if err == nil {
+				t.Error("Expected error, got nil")
+			}
+		default:
+			t.Error("Expected error handler to be called")
+		}
+	})
+	t.Run("recorded body limit", func(t *testing.T) {
+		m := New("key").Middleware(extract)
+		m.MaxRecordedBody = 5
+		handler := m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("you said: "))
+			w.Write([]byte("hello"))
+		}))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/?user=abc", strings.NewReader("hello")))
+		if rec.Body.String() != "you said: hello" {
+			t.Errorf("Expected response not to be truncated, got %v", rec.Body.String())
+		}
+		select {
+		case messages := <-submitted:
+			if len(messages) != 2 || messages[1].Message != "you s" {
+				t.Errorf("Unexpected messages %v", messages)
+			}
+		case <-time.After(time.Second):
+			t.Error("Expected messages to be submitted")
+		}
+	})
+	t.Run("body read error", func(t *testing.T) {
+		m := New("key").Middleware(extract)
+		m.ErrorHandler = func(err error) {}
+		var received string
+		handler := m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+			received = string(b)
+		}))
+		body := io.MultiReader(strings.NewReader("partial"), failingReader{})
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", body))
+		if received != "partial" {
+			t.Errorf("Expected consumed bytes to be restored, got %q", received)
+		}
+	})
+}
+
+func TestMiddleware_Hijack(t *testing.T) {
+	oldEndpoint := messagesEndpoint
+	defer func() { messagesEndpoint = oldEndpoint }()
+	chatbaseServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"all_succeeded":true,"status":200}`))
+	}))
+	defer chatbaseServer.Close()
+	messagesEndpoint = chatbaseServer.URL
+
+	wrapped := New("key").Middleware(func(r *http.Request) (string, string, string, error) {
+		return "user", "hi", PlatformWeb, nil
+	})
+	wrapped.ErrorHandler = func(err error) {}
+	hijacked := make(chan bool, 1)
+	ts := httptest.NewServer(wrapped.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, ok := w.(http.Hijacker)
+		if !ok {
+			hijacked <- false
+			return
+		}
+		conn, _, err := h.Hijack()
+		if err == nil {
+			conn.Close()
+		}
+		hijacked <- err == nil
+	})))
+	defer ts.Close()
+	http.Get(ts.URL)
+	if !<-hijacked {
+		t.Error("Expected connection to be hijacked")
+	}
+}
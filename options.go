@@ -0,0 +1,152 @@
+package chatbase
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Option configures a Client
+type Option func(*config)
+
+// config contains the settings shared by a client and its payloads
+type config struct {
+	observer     PipelineObserver
+	logger       *slog.Logger
+	interceptors []Interceptor
+	consent      *ConsentRegistry
+	sampler      *Sampler
+
+	version         string
+	platform        string
+	sessionResolver SessionResolver
+	eventProperties []EventProperty
+}
+
+// PipelineObserver is notified about items that are waiting for submission
+// and items that are dropped before being submitted, e.g. for exporting
+// metrics. Implementations need to be safe for concurrent use
+type PipelineObserver interface {
+	// Queued is called when the number of items waiting in the given
+	// pipeline changes by delta
+	Queued(pipeline string, delta int)
+	// Dropped is called when count items have been dropped by the
+	// given pipeline
+	Dropped(pipeline string, count int)
+}
+
+// WithPipelineObserver notifies the given observer about items queued
+// or dropped by pipelines like FacebookTransport, consent and sampling
+func WithPipelineObserver(o PipelineObserver) Option {
+	return func(c *config) {
+		c.observer = o
+	}
+}
+
+// observe records the outcome of an API call
+func (c *config) observe(ctx context.Context, info *call, err error) {
+	if c == nil {
+		return
+	}
+	c.logCall(ctx, info, err)
+}
+
+// queued adjusts the number of items waiting for submission in a pipeline
+func (c *config) queued(pipeline string, delta int) {
+	if c == nil {
+		return
+	}
+	if c.observer != nil {
+		c.observer.Queued(pipeline, delta)
+	}
+}
+
+// dropped records an item that has been dropped before submission
+func (c *config) dropped(pipeline, reason string) {
+	if c == nil {
+		return
+	}
+	if c.observer != nil {
+		c.observer.Dropped(pipeline, 1)
+	}
+	c.logDropped(pipeline, reason)
+}
+
+// withholding tracks the items of a payload that have been withheld
+// while it passes the consent registry and sampler
+type withholding struct {
+	// pipelines holds the pipeline that withheld each item of the original
+	// payload or an empty string for items that are submitted
+	pipelines []string
+	// remaining maps the items of the filtered payload to the original ones
+	remaining []int
+}
+
+func newWithholding(v interface{}) *withholding {
+	_, n := describePayload(v)
+	w := &withholding{pipelines: make([]string, n), remaining: make([]int, n)}
+	for i := range w.remaining {
+		w.remaining[i] = i
+	}
+	return w
+}
+
+// withhold marks the items at the given ascending indices of the
+// filtered payload as withheld by the given pipeline
+func (w *withholding) withhold(pipeline string, indices []int) {
+	remaining := w.remaining[:0]
+	for i, original := range w.remaining {
+		if len(indices) > 0 && indices[0] == i {
+			w.pipelines[original] = pipeline
+			indices = indices[1:]
+			continue
+		}
+		remaining = append(remaining, original)
+	}
+	w.remaining = remaining
+}
+
+// gate returns the part of a payload that may be submitted according to
+// the consent registry and sampler of the config, in that order, or nil
+// if nothing may be submitted. The returned slice contains the pipeline
+// that withheld each item and is nil when all items are submitted
+func (c *config) gate(ctx context.Context, v interface{}) (interface{}, []string, error) {
+	if c == nil || (c.consent == nil && c.sampler == nil) {
+		return v, nil, nil
+	}
+	w := newWithholding(v)
+	var err error
+	if c.consent != nil {
+		if v, err = c.withhold(ctx, consentPipeline, v, c.consent.apply, w); err != nil || v == nil {
+			return nil, w.pipelines, err
+		}
+	}
+	if c.sampler != nil {
+		if v, err = c.withhold(ctx, samplingPipeline, v, c.sampler.apply, w); err != nil || v == nil {
+			return nil, w.pipelines, err
+		}
+	}
+	if len(w.remaining) == len(w.pipelines) {
+		return v, nil, nil
+	}
+	return v, w.pipelines, nil
+}
+
+// withhold applies the given filter to a payload and records the
+// items that have been removed as dropped
+func (c *config) withhold(ctx context.Context, pipeline string, v interface{}, filter func(interface{}) (interface{}, []int, error), w *withholding) (interface{}, error) {
+	result, withheld, err := filter(v)
+	if err != nil {
+		return nil, err
+	}
+	w.withhold(pipeline, withheld)
+	if len(withheld) > 0 && c.observer != nil {
+		c.observer.Dropped(pipeline, len(withheld))
+	}
+	if len(withheld) > 0 && c.logger != nil {
+		c.logger.LogAttrs(ctx, slog.LevelDebug, "chatbase withheld items",
+			slog.String("pipeline", pipeline),
+			slog.Int("items", len(withheld)),
+		)
+	}
+	return result, nil
+}
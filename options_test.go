@@ -0,0 +1,89 @@
+package chatbase
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingObserver is a PipelineObserver keeping track of all calls
+type recordingObserver struct {
+	mu    sync.Mutex
+	queue map[string]int
+	drops map[string]int
+}
+
+func (o *recordingObserver) Queued(pipeline string, delta int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.queue == nil {
+		o.queue = map[string]int{}
+	}
+	o.queue[pipeline] += delta
+}
+
+func (o *recordingObserver) Dropped(pipeline string, count int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.drops == nil {
+		o.drops = map[string]int{}
+	}
+	o.drops[pipeline] += count
+}
+
+func (o *recordingObserver) queued(pipeline string) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.queue[pipeline]
+}
+
+func (o *recordingObserver) dropped(pipeline string) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.drops[pipeline]
+}
+
+func TestWithPipelineObserver(t *testing.T) {
+	oldEndpoint := facebookRequestsEndpoint
+	defer func() { facebookRequestsEndpoint = oldEndpoint }()
+
+	chatbaseServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"all_succeeded":true,"status":200}`))
+	}))
+	defer chatbaseServer.Close()
+	facebookRequestsEndpoint = chatbaseServer.URL
+
+	sendAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"recipient_id":"1","message_id":"mid"}`))
+	}))
+	defer sendAPI.Close()
+
+	observer := &recordingObserver{}
+	transport := New("key", WithPipelineObserver(observer)).FacebookTransport(nil)
+	transport.FlushInterval = time.Hour
+	httpClient := &http.Client{Transport: transport}
+	send := func() {
+		res, err := httpClient.Post(sendAPI.URL+"/me/messages", "application/json", strings.NewReader(`{"recipient":{"id":"1"}}`))
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		res.Body.Close()
+	}
+
+	send()
+	send()
+	if v := observer.queued(facebookTransportPipeline); v != 2 {
+		t.Errorf("Expected queue depth of 2, got %v", v)
+	}
+	transport.Close()
+	send()
+	if v := observer.queued(facebookTransportPipeline); v != 0 {
+		t.Errorf("Expected empty queue, got %v", v)
+	}
+	if v := observer.dropped(facebookTransportPipeline); v != 1 {
+		t.Errorf("Expected 1 dropped item, got %v", v)
+	}
+}
@@ -0,0 +1,250 @@
+package chatbase
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// Pseudonymizer replaces user ids with keyed hashes before payloads are
+// submitted, so Chatbase never receives the original identifiers. The
+// same user id always results in the same pseudonym until the secret
+// is rotated. It is applied to the user and session ids of messages, the
+// user ids of events, the sender ids of Facebook messages and the recipient
+// ids of Facebook Send API calls. Session ids are pseudonymized as well
+// since adapters commonly derive them from user or chat ids
+type Pseudonymizer struct {
+	mu     sync.RWMutex
+	secret []byte
+	table  *LookupTable
+}
+
+// NewPseudonymizer returns a new Pseudonymizer using the given secret
+func NewPseudonymizer(secret []byte) *Pseudonymizer {
+	return &Pseudonymizer{
+		secret: secret,
+	}
+}
+
+// WithPseudonymizer replaces the user ids of all payloads created by the
+// client before they are submitted. The pseudonymizer is added to the
+// client's interceptors
+func WithPseudonymizer(p *Pseudonymizer) Option {
+	return WithInterceptors(p.Intercept)
+}
+
+// SetLookupTable records the original user id of each pseudonym in the
+// given table
+func (p *Pseudonymizer) SetLookupTable(t *LookupTable) *Pseudonymizer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.table = t
+	return p
+}
+
+// Rotate replaces the secret. User ids submitted afterwards will result
+// in new pseudonyms
+func (p *Pseudonymizer) Rotate(secret []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.secret = secret
+}
+
+// Pseudonym returns the pseudonym for the given user id. Empty ids are
+// returned as is
+func (p *Pseudonymizer) Pseudonym(userID string) (string, error) {
+	if userID == "" {
+		return userID, nil
+	}
+	p.mu.RLock()
+	mac := hmac.New(sha256.New, p.secret)
+	table := p.table
+	p.mu.RUnlock()
+	mac.Write([]byte(userID))
+	pseudonym := hex.EncodeToString(mac.Sum(nil))[:32]
+	if err := table.store(pseudonym, userID); err != nil {
+		return "", err
+	}
+	return pseudonym, nil
+}
+
+// Intercept is an Interceptor replacing the request's payload with a
+// pseudonymized copy. The caller's payload is not modified
+func (p *Pseudonymizer) Intercept(ctx context.Context, req *APIRequest, next APICall) (*APIResponse, error) {
+	// errors of the lookup table are collected here as the
+	// transformation of messages and events cannot fail
+	var tableErr error
+	pseudonym := func(userID string) string {
+		result, err := p.Pseudonym(userID)
+		if err != nil && tableErr == nil {
+			tableErr = err
+		}
+		return result
+	}
+	payload, err := transform{
+		message: func(m Message) Message {
+			m.UserID = pseudonym(m.UserID)
+			m.SessionID = pseudonym(m.SessionID)
+			return m
+		},
+		event: func(e Event) Event {
+			e.UserID = pseudonym(e.UserID)
+			return e
+		},
+		facebookMessage: func(f FacebookMessage) (FacebookMessage, error) {
+			payload, err := pseudonymizeJSON(f.Payload, pseudonym, "sender")
+			f.Payload = payload
+			return f, err
+		},
+		requestResponse: func(f FacebookRequestResponse) (FacebookRequestResponse, error) {
+			request, err := pseudonymizeJSON(f.Request, pseudonym, "recipient")
+			if err != nil {
+				return f, err
+			}
+			response, err := pseudonymizeJSON(f.Response, pseudonym, "recipient")
+			if err != nil {
+				return f, err
+			}
+			f.Request, f.Response = request, response
+			return f, nil
+		},
+	}.apply(req.Payload)
+	if err == nil {
+		err = tableErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	req.Payload = payload
+	return next(ctx, req)
+}
+
+// pseudonymizeJSON returns a generic copy of v where the ids of all
+// objects stored under the given key, as well as all fields named
+// key + "_id", have been replaced
+func pseudonymizeJSON(v interface{}, pseudonym func(string) string, key string) (interface{}, error) {
+	generic, err := genericJSON(v)
+	if err != nil {
+		return nil, err
+	}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch value := v.(type) {
+		case map[string]interface{}:
+			for k, child := range value {
+				if account, ok := child.(map[string]interface{}); ok && k == key {
+					if id, ok := account["id"].(string); ok {
+						account["id"] = pseudonym(id)
+					}
+				}
+				if id, ok := child.(string); ok && k == key+"_id" {
+					value[k] = pseudonym(id)
+					continue
+				}
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range value {
+				walk(child)
+			}
+		}
+	}
+	walk(generic)
+	return generic, nil
+}
+
+// LookupTable maps pseudonyms back to the original user ids, e.g. for
+// support investigations. It is kept locally and never submitted
+type LookupTable struct {
+	mu   sync.RWMutex
+	ids  map[string]string
+	file *os.File
+}
+
+// lookupEntry is a single line of a lookup table file
+type lookupEntry struct {
+	Pseudonym string `json:"pseudonym"`
+	UserID    string `json:"user_id"`
+}
+
+// NewLookupTable returns a new LookupTable kept in memory
+func NewLookupTable() *LookupTable {
+	return &LookupTable{
+		ids: map[string]string{},
+	}
+}
+
+// OpenLookupTable returns a LookupTable that is persisted to the file
+// at the given path. Existing entries are loaded, new entries are
+// appended as JSON lines
+func OpenLookupTable(path string) (*LookupTable, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	t := NewLookupTable()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry lookupEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			file.Close()
+			return nil, err
+		}
+		t.ids[entry.Pseudonym] = entry.UserID
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	t.file = file
+	return t, nil
+}
+
+// Lookup returns the original user id of the given pseudonym
+func (t *LookupTable) Lookup(pseudonym string) (string, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	userID, ok := t.ids[pseudonym]
+	return userID, ok
+}
+
+// Close closes the file backing the table
+func (t *LookupTable) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.file == nil {
+		return nil
+	}
+	err := t.file.Close()
+	t.file = nil
+	return err
+}
+
+func (t *LookupTable) store(pseudonym, userID string) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.ids[pseudonym]; ok {
+		return nil
+	}
+	t.ids[pseudonym] = userID
+	if t.file == nil {
+		return nil
+	}
+	b, err := json.Marshal(lookupEntry{Pseudonym: pseudonym, UserID: userID})
+	if err != nil {
+		return err
+	}
+	_, err = t.file.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,209 @@
+package chatbase
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPseudonymizer_Pseudonym(t *testing.T) {
+	p := NewPseudonymizer([]byte("secret"))
+	first, _ := p.Pseudonym("user-1")
+	if len(first) != 32 || first == "user-1" {
+		t.Fatalf("Unexpected pseudonym %q", first)
+	}
+	if again, _ := p.Pseudonym("user-1"); again != first {
+		t.Errorf("Expected stable pseudonym, got %q and %q", first, again)
+	}
+	if other, _ := p.Pseudonym("user-2"); other == first {
+		t.Errorf("Expected different pseudonyms, got %q", other)
+	}
+	if empty, _ := p.Pseudonym(""); empty != "" {
+		t.Errorf("Expected empty id to be kept, got %q", empty)
+	}
+	p.Rotate([]byte("rotated"))
+	if rotated, _ := p.Pseudonym("user-1"); rotated == first {
+		t.Errorf("Expected new pseudonym after rotation, got %q", rotated)
+	}
+}
+
+func TestPseudonymizer_Intercept(t *testing.T) {
+	p := NewPseudonymizer([]byte("secret"))
+	id := func(userID string) string {
+		pseudonym, _ := p.Pseudonym(userID)
+		return pseudonym
+	}
+	tests := []struct {
+		name     string
+		payload  interface{}
+		expected interface{}
+	}{
+		{
+			"message",
+			&Message{UserID: "user", Message: "hi"},
+			&Message{UserID: id("user"), Message: "hi"},
+		},
+		{
+			"message with session",
+			&Message{UserID: "user", SessionID: "user"},
+			&Message{UserID: id("user"), SessionID: id("user")},
+		},
+		{
+			"messages",
+			&Messages{{UserID: "a"}, {UserID: "b"}},
+			&Messages{{UserID: id("a")}, {UserID: id("b")}},
+		},
+		{
+			"events",
+			&Events{{UserID: "a", Intent: "signup"}},
+			&Events{{UserID: id("a"), Intent: "signup"}},
+		},
+		{
+			"facebook message",
+			&FacebookMessage{Payload: json.RawMessage(`{"sender":{"id":"123"},"recipient":{"id":"page"},"message":{"text":"hi"}}`)},
+			&FacebookMessage{Payload: map[string]interface{}{
+				"sender":    map[string]interface{}{"id": id("123")},
+				"recipient": map[string]interface{}{"id": "page"},
+				"message":   map[string]interface{}{"text": "hi"},
+			}},
+		},
+		{
+			"facebook request responses",
+			&FacebookRequestResponses{{
+				Request:  map[string]interface{}{"recipient": map[string]interface{}{"id": "123"}},
+				Response: map[string]interface{}{"recipient_id": "123", "message_id": "mid"},
+			}},
+			&FacebookRequestResponses{{
+				Request:  map[string]interface{}{"recipient": map[string]interface{}{"id": id("123")}},
+				Response: map[string]interface{}{"recipient_id": id("123"), "message_id": "mid"},
+			}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			before, _ := json.Marshal(test.payload)
+			var result interface{}
+			_, err := p.Intercept(context.Background(), &APIRequest{Payload: test.payload}, func(ctx context.Context, req *APIRequest) (*APIResponse, error) {
+				result = req.Payload
+				return &APIResponse{}, nil
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(test.expected, result) {
+				t.Errorf("Expected %#v, got %#v", test.expected, result)
+			}
+			if after, _ := json.Marshal(test.payload); string(before) != string(after) {
+				t.Errorf("Expected payload to be unchanged, got %s", after)
+			}
+		})
+	}
+}
+
+func TestLookupTable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chatbase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "pseudonyms.jsonl")
+
+	table, err := OpenLookupTable(path)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	p := NewPseudonymizer([]byte("secret")).SetLookupTable(table)
+	first, _ := p.Pseudonym("user-1")
+	p.Pseudonym("user-1")
+	p.Rotate([]byte("rotated"))
+	second, _ := p.Pseudonym("user-1")
+	if err := table.Close(); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	b, _ := ioutil.ReadFile(path)
+	expected := `{"pseudonym":"` + first + `","user_id":"user-1"}` + "\n" +
+		`{"pseudonym":"` + second + `","user_id":"user-1"}` + "\n"
+	if string(b) != expected {
+		t.Errorf("Expected file contents %q, got %q", expected, b)
+	}
+
+	reopened, err := OpenLookupTable(path)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	defer reopened.Close()
+	for _, pseudonym := range []string{first, second} {
+		if userID, ok := reopened.Lookup(pseudonym); !ok || userID != "user-1" {
+			t.Errorf("Expected %q to resolve to user-1, got %q %v", pseudonym, userID, ok)
+		}
+	}
+	if _, ok := reopened.Lookup("unknown"); ok {
+		t.Error("Expected unknown pseudonym not to resolve")
+	}
+
+	ioutil.WriteFile(path, []byte("not json\n"), 0600)
+	if _, err := OpenLookupTable(path); err == nil {
+		t.Error("Expected error for invalid file, got nil")
+	}
+}
+
+func TestWithPseudonymizer_SessionID(t *testing.T) {
+	oldEndpoint := messageEndpoint
+	defer func() { messageEndpoint = oldEndpoint }()
+
+	var received []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte(`{"message_id":"1","status":200}`))
+	}))
+	defer ts.Close()
+	messageEndpoint = ts.URL
+
+	c := New("key", WithPseudonymizer(NewPseudonymizer([]byte("secret"))))
+	if _, err := c.UserMessage("4915112345678", PlatformSMS).SetSessionID("4915112345678").Submit(); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if strings.Contains(string(received), "4915112345678") {
+		t.Errorf("Expected raw identifier not to be submitted, got %s", received)
+	}
+	var m Message
+	json.Unmarshal(received, &m)
+	if m.SessionID == "" || m.SessionID != m.UserID {
+		t.Errorf("Expected session id to use the user's pseudonym, got %#v", m)
+	}
+}
+
+func TestWithPseudonymizer(t *testing.T) {
+	oldEndpoint := eventEndpoint
+	defer func() { eventEndpoint = oldEndpoint }()
+
+	var received Event
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(b, &received)
+		w.Write([]byte(`{"status":200}`))
+	}))
+	defer ts.Close()
+	eventEndpoint = ts.URL
+
+	table := NewLookupTable()
+	c := New("key", WithPseudonymizer(NewPseudonymizer([]byte("secret")).SetLookupTable(table)))
+	e := c.Event("jane@example.com", "signup")
+	if err := e.Submit(); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if userID, ok := table.Lookup(received.UserID); !ok || userID != "jane@example.com" {
+		t.Errorf("Expected pseudonym %q to resolve, got %q %v", received.UserID, userID, ok)
+	}
+	if e.UserID != "jane@example.com" {
+		t.Errorf("Expected event to be unchanged, got %q", e.UserID)
+	}
+}
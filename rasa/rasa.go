@@ -0,0 +1,345 @@
+/*
+Package rasa imports conversations stored in Rasa tracker stores into Chatbase.
+
+User events are recorded as user messages using the intent found in their
+parse data, bot events as agent messages. User messages are marked as not
+handled when a fallback action is triggered in response or when the intent's
+confidence is below the configured threshold. Custom actions and slot changes
+are recorded as events:
+
+	importer := rasa.New(chatbase.New("MY-API-KEY"))
+	importer.Threshold = 0.4
+	responses, err := importer.Import(trackerDump)
+*/
+package rasa
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+// Types of tracker events handled by the importer
+const (
+	UserEvent   = "user"
+	BotEvent    = "bot"
+	ActionEvent = "action"
+	SlotEvent   = "slot"
+)
+
+// SlotSetIntent is the intent of events recorded for slot changes
+const SlotSetIntent = "slot_set"
+
+// DefaultChunkSize is the number of messages or events submitted in a
+// single request when no ChunkSize is configured
+const DefaultChunkSize = 100
+
+// DefaultFallbackActions are the actions marking the preceding user
+// message as not handled when no FallbackActions are configured
+var DefaultFallbackActions = []string{
+	"action_default_fallback",
+	"action_two_stage_fallback",
+}
+
+// DefaultFallbackIntents are the intents that are always recorded as not
+// handled when no FallbackIntents are configured
+var DefaultFallbackIntents = []string{"nlu_fallback"}
+
+// builtinActions are actions defined by Rasa itself that are not
+// recorded as events
+var builtinActions = map[string]bool{
+	"action_listen":                  true,
+	"action_restart":                 true,
+	"action_session_start":           true,
+	"action_default_fallback":        true,
+	"action_deactivate_loop":         true,
+	"action_deactivate_form":         true,
+	"action_revert_fallback_events":  true,
+	"action_default_ask_affirmation": true,
+	"action_default_ask_rephrase":    true,
+	"action_two_stage_fallback":      true,
+	"action_back":                    true,
+	"action_unlikely_intent":         true,
+	"action_extract_slots":           true,
+}
+
+// channels maps Rasa input channels to Chatbase platforms
+var channels = map[string]string{
+	"facebook":     chatbase.PlatformFacebook,
+	"slack":        chatbase.PlatformSlack,
+	"telegram":     chatbase.PlatformTelegram,
+	"twilio":       chatbase.PlatformSMS,
+	"botframework": chatbase.PlatformSkype,
+	"rest":         chatbase.PlatformWeb,
+	"socketio":     chatbase.PlatformWeb,
+	"webexteams":   "Webex",
+	"mattermost":   "Mattermost",
+	"rocketchat":   "Rocket.Chat",
+}
+
+// Tracker is the state of a single conversation as stored by Rasa
+type Tracker struct {
+	SenderID string  `json:"sender_id"`
+	Events   []Event `json:"events"`
+}
+
+// Event is a single event of a tracker
+type Event struct {
+	Event        string      `json:"event"`
+	Timestamp    float64     `json:"timestamp"`
+	Text         string      `json:"text,omitempty"`
+	ParseData    *ParseData  `json:"parse_data,omitempty"`
+	InputChannel string      `json:"input_channel,omitempty"`
+	Name         string      `json:"name,omitempty"`
+	Value        interface{} `json:"value,omitempty"`
+	Policy       string      `json:"policy,omitempty"`
+	Confidence   *float64    `json:"confidence,omitempty"`
+}
+
+// ParseData is the result of parsing a user message
+type ParseData struct {
+	Intent   Intent   `json:"intent"`
+	Entities []Entity `json:"entities,omitempty"`
+	Text     string   `json:"text,omitempty"`
+}
+
+// Intent is an intent predicted for a user message
+type Intent struct {
+	Name       string  `json:"name"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Entity is an entity extracted from a user message
+type Entity struct {
+	Entity     string      `json:"entity"`
+	Value      interface{} `json:"value"`
+	Confidence float64     `json:"confidence_entity,omitempty"`
+}
+
+// Importer maps tracker events to messages and events
+type Importer struct {
+	Client *chatbase.Client
+	// Platform is used when the input channel of a conversation is unknown
+	Platform string
+	// Threshold is the minimum intent confidence for user messages to be
+	// recorded as handled. Zero disables the check
+	Threshold float64
+	// FallbackActions overrides DefaultFallbackActions
+	FallbackActions []string
+	// FallbackIntents overrides DefaultFallbackIntents
+	FallbackIntents []string
+	// ChunkSize limits the number of messages or events per submission
+	ChunkSize int
+}
+
+// New returns a new Importer using the given client
+func New(client *chatbase.Client) *Importer {
+	return &Importer{
+		Client: client,
+	}
+}
+
+// Decode reads all trackers from r. The data can contain any number of
+// tracker objects or arrays of tracker objects, like a tracker store dump
+// or the responses of Rasa's HTTP API
+func Decode(r io.Reader) ([]Tracker, error) {
+	var trackers []Tracker
+	dec := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == io.EOF {
+			return trackers, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if trimmed := strings.TrimSpace(string(raw)); strings.HasPrefix(trimmed, "[") {
+			var batch []Tracker
+			if err := json.Unmarshal(raw, &batch); err != nil {
+				return nil, err
+			}
+			trackers = append(trackers, batch...)
+			continue
+		}
+		var tracker Tracker
+		if err := json.Unmarshal(raw, &tracker); err != nil {
+			return nil, err
+		}
+		trackers = append(trackers, tracker)
+	}
+}
+
+// Convert maps the events of a tracker to messages and events. The sender
+// id is used as user and session id
+func (i *Importer) Convert(t *Tracker) (chatbase.Messages, chatbase.Events) {
+	messages := chatbase.Messages{}
+	events := chatbase.Events{}
+	platform := i.Platform
+	// lastUser is the index of the latest user message, it is reset
+	// as soon as the bot has responded so fallbacks are only applied
+	// to the message they were triggered by
+	lastUser := -1
+
+	for _, e := range t.Events {
+		ts := timeStamp(e.Timestamp)
+		switch e.Event {
+		case UserEvent:
+			if p, ok := channels[e.InputChannel]; ok {
+				platform = p
+			} else if e.InputChannel != "" && i.Platform == "" {
+				platform = e.InputChannel
+			}
+			m := i.Client.UserMessage(t.SenderID, platform).
+				SetMessage(e.Text).
+				SetSessionID(t.SenderID).
+				SetTimeStamp(ts)
+			if e.ParseData != nil {
+				intent := e.ParseData.Intent
+				m.SetIntent(intent.Name)
+				m.SetNotHandled(i.isFallbackIntent(intent.Name) ||
+					(i.Threshold > 0 && intent.Confidence < i.Threshold))
+			}
+			messages.Append(m)
+			lastUser = len(messages) - 1
+		case BotEvent:
+			messages.Append(i.Client.AgentMessage(t.SenderID, platform).
+				SetMessage(e.Text).
+				SetSessionID(t.SenderID).
+				SetTimeStamp(ts))
+			lastUser = -1
+		case ActionEvent:
+			if i.isFallbackAction(e.Name) {
+				if lastUser >= 0 {
+					messages[lastUser].SetNotHandled(true)
+				}
+				continue
+			}
+			if builtinActions[e.Name] || strings.HasPrefix(e.Name, "utter_") {
+				continue
+			}
+			event := i.Client.Event(t.SenderID, e.Name).
+				SetPlatform(platform).
+				SetTimeStamp(ts)
+			if e.Policy != "" {
+				event.AddProperty("policy", e.Policy)
+			}
+			if e.Confidence != nil {
+				event.AddProperty("confidence", *e.Confidence)
+			}
+			events.Append(event)
+		case SlotEvent:
+			event := i.Client.Event(t.SenderID, SlotSetIntent).
+				SetPlatform(platform).
+				SetTimeStamp(ts)
+			event.AddProperty("slot", e.Name)
+			if value, ok := propertyValue(e.Value); ok {
+				event.AddProperty("value", value)
+			}
+			events.Append(event)
+		}
+	}
+	return messages, events
+}
+
+// Read decodes all trackers in r and converts their events
+func (i *Importer) Read(r io.Reader) (chatbase.Messages, chatbase.Events, error) {
+	trackers, err := Decode(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	messages := chatbase.Messages{}
+	events := chatbase.Events{}
+	for n := range trackers {
+		m, e := i.Convert(&trackers[n])
+		messages = append(messages, m...)
+		events = append(events, e...)
+	}
+	return messages, events, nil
+}
+
+// Import reads all trackers in r and submits the resulting messages and
+// events in chunks
+func (i *Importer) Import(r io.Reader) ([]*chatbase.MessagesResponse, error) {
+	messages, events, err := i.Read(r)
+	if err != nil {
+		return nil, err
+	}
+
+	size := i.ChunkSize
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	var responses []*chatbase.MessagesResponse
+	for start := 0; start < len(messages); start += size {
+		end := start + size
+		if end > len(messages) {
+			end = len(messages)
+		}
+		chunk := messages[start:end]
+		res, err := chunk.Submit()
+		if err != nil {
+			return responses, fmt.Errorf("submitting messages %d to %d failed: %v", start+1, end, err)
+		}
+		responses = append(responses, res)
+	}
+	for start := 0; start < len(events); start += size {
+		end := start + size
+		if end > len(events) {
+			end = len(events)
+		}
+		chunk := events[start:end]
+		if err := chunk.Submit(); err != nil {
+			return responses, fmt.Errorf("submitting events %d to %d failed: %v", start+1, end, err)
+		}
+	}
+	return responses, nil
+}
+
+func (i *Importer) isFallbackAction(name string) bool {
+	actions := i.FallbackActions
+	if actions == nil {
+		actions = DefaultFallbackActions
+	}
+	return contains(actions, name)
+}
+
+func (i *Importer) isFallbackIntent(name string) bool {
+	intents := i.FallbackIntents
+	if intents == nil {
+		intents = DefaultFallbackIntents
+	}
+	return contains(intents, name)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// propertyValue converts slot values into values accepted by event
+// properties, encoding lists and objects as JSON
+func propertyValue(v interface{}) (interface{}, bool) {
+	switch value := v.(type) {
+	case nil:
+		return nil, false
+	case string, bool, float64:
+		return value, true
+	default:
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, false
+		}
+		return string(b), true
+	}
+}
+
+// timeStamp converts Rasa's fractional UNIX seconds into milliseconds
+func timeStamp(seconds float64) int64 {
+	return int64(seconds*1000 + 0.5)
+}
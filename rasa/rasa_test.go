@@ -0,0 +1,150 @@
+package rasa
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+const tracker = `{
+	"sender_id": "user-1",
+	"events": [
+		{"event": "action", "name": "action_session_start", "timestamp": 1600000000.0},
+		{"event": "slot", "name": "session_started_metadata", "timestamp": 1600000000.001},
+		{"event": "user", "timestamp": 1600000001.5, "text": "hi", "input_channel": "telegram", "parse_data": {"intent": {"name": "greet", "confidence": 0.98}}},
+		{"event": "action", "name": "utter_greet", "timestamp": 1600000001.6, "policy": "RulePolicy", "confidence": 1.0},
+		{"event": "bot", "timestamp": 1600000001.7, "text": "Hello!"},
+		{"event": "action", "name": "action_listen", "timestamp": 1600000001.8},
+		{"event": "user", "timestamp": 1600000002, "text": "blargh", "input_channel": "telegram", "parse_data": {"intent": {"name": "order", "confidence": 0.2}}},
+		{"event": "action", "name": "action_default_fallback", "timestamp": 1600000002.1},
+		{"event": "bot", "timestamp": 1600000002.2, "text": "Sorry?"},
+		{"event": "user", "timestamp": 1600000003, "text": "a pizza", "input_channel": "telegram", "parse_data": {"intent": {"name": "order", "confidence": 0.3}}},
+		{"event": "action", "name": "action_place_order", "timestamp": 1600000003.1, "policy": "TEDPolicy", "confidence": 0.75},
+		{"event": "slot", "name": "toppings", "value": ["cheese"], "timestamp": 1600000003.2},
+		{"event": "user", "timestamp": 1600000004, "text": "?", "input_channel": "telegram", "parse_data": {"intent": {"name": "nlu_fallback", "confidence": 0.9}}}
+	]
+}`
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"single", `{"sender_id":"a"}`, []string{"a"}},
+		{"stream", "{\"sender_id\":\"a\"}\n{\"sender_id\":\"b\"}", []string{"a", "b"}},
+		{"array", `[{"sender_id":"a"},{"sender_id":"b"}] {"sender_id":"c"}`, []string{"a", "b", "c"}},
+		{"empty", "", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			trackers, err := Decode(strings.NewReader(test.input))
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			var ids []string
+			for _, tr := range trackers {
+				ids = append(ids, tr.SenderID)
+			}
+			if !reflect.DeepEqual(test.expected, ids) {
+				t.Errorf("Expected %v, got %v", test.expected, ids)
+			}
+		})
+	}
+	if _, err := Decode(strings.NewReader(`{"sender_id":`)); err == nil {
+		t.Error("Expected error for invalid input")
+	}
+}
+
+func TestConvert(t *testing.T) {
+	var tr Tracker
+	if err := json.Unmarshal([]byte(tracker), &tr); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	importer := New(chatbase.New("key"))
+	importer.Threshold = 0.25
+
+	messages, events := importer.Convert(&tr)
+
+	type summary struct {
+		Type       chatbase.MessageType
+		Message    string
+		Intent     string
+		NotHandled bool
+		TimeStamp  int64
+		Platform   string
+	}
+	expected := []summary{
+		{chatbase.UserType, "hi", "greet", false, 1600000001500, "Telegram"},
+		{chatbase.AgentType, "Hello!", "", false, 1600000001700, "Telegram"},
+		{chatbase.UserType, "blargh", "order", true, 1600000002000, "Telegram"},
+		{chatbase.AgentType, "Sorry?", "", false, 1600000002200, "Telegram"},
+		{chatbase.UserType, "a pizza", "order", false, 1600000003000, "Telegram"},
+		{chatbase.UserType, "?", "nlu_fallback", true, 1600000004000, "Telegram"},
+	}
+	var actual []summary
+	for _, m := range messages {
+		if m.UserID != "user-1" || m.SessionID != "user-1" {
+			t.Errorf("Unexpected ids in %v", m)
+		}
+		actual = append(actual, summary{m.Type, m.Message, m.Intent, m.NotHandled, m.TimeStamp, m.Platform})
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+
+	policy, _ := chatbase.NewEventProperty("policy", "TEDPolicy")
+	confidence, _ := chatbase.NewEventProperty("confidence", 0.75)
+	metadataSlot, _ := chatbase.NewEventProperty("slot", "session_started_metadata")
+	toppingsSlot, _ := chatbase.NewEventProperty("slot", "toppings")
+	toppingsValue, _ := chatbase.NewEventProperty("value", `["cheese"]`)
+	expectedEvents := chatbase.Events{
+		{APIKey: "key", UserID: "user-1", Intent: SlotSetIntent, TimeStamp: 1600000000001, Properties: []chatbase.EventProperty{metadataSlot}},
+		{APIKey: "key", UserID: "user-1", Intent: "action_place_order", TimeStamp: 1600000003100, Platform: "Telegram", Properties: []chatbase.EventProperty{policy, confidence}},
+		{APIKey: "key", UserID: "user-1", Intent: SlotSetIntent, TimeStamp: 1600000003200, Platform: "Telegram", Properties: []chatbase.EventProperty{toppingsSlot, toppingsValue}},
+	}
+	if !reflect.DeepEqual(expectedEvents, events) {
+		t.Errorf("Expected %#v, got %#v", expectedEvents, events)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (r roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r(req)
+}
+
+func TestImport(t *testing.T) {
+	var paths []string
+	chatbase.SetAPITransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		paths = append(paths, r.URL.Path)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"all_succeeded":true,"status":200}`)),
+		}, nil
+	}))
+	defer chatbase.SetAPITransport(nil)
+
+	importer := New(chatbase.New("key"))
+	importer.ChunkSize = 4
+	responses, err := importer.Import(strings.NewReader(tracker))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(responses) != 2 {
+		t.Errorf("Expected 2 responses, got %d", len(responses))
+	}
+	expected := []string{
+		"/api/messages",
+		"/api/messages",
+		"/apis/v1/events/insert_batch",
+	}
+	if !reflect.DeepEqual(expected, paths) {
+		t.Errorf("Expected %v, got %v", expected, paths)
+	}
+}
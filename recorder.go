@@ -0,0 +1,70 @@
+package chatbase
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+)
+
+// DefaultMaxRecordedBody is the number of bytes of a response body that
+// is recorded by a ResponseRecorder when no limit is given
+const DefaultMaxRecordedBody = 64 << 10
+
+// ResponseRecorder is an http.ResponseWriter recording the beginning of the
+// response body while passing it on unchanged. Flushing, hijacking and server
+// push are forwarded to the underlying ResponseWriter
+type ResponseRecorder struct {
+	http.ResponseWriter
+	body  bytes.Buffer
+	limit int
+}
+
+// NewResponseRecorder returns a new ResponseRecorder wrapping w that records
+// up to limit bytes, DefaultMaxRecordedBody is used when limit is not positive
+func NewResponseRecorder(w http.ResponseWriter, limit int) *ResponseRecorder {
+	if limit <= 0 {
+		limit = DefaultMaxRecordedBody
+	}
+	return &ResponseRecorder{ResponseWriter: w, limit: limit}
+}
+
+// Body returns the recorded part of the response body
+func (r *ResponseRecorder) Body() []byte {
+	return r.body.Bytes()
+}
+
+func (r *ResponseRecorder) Write(b []byte) (int, error) {
+	if remaining := r.limit - r.body.Len(); remaining > 0 {
+		if len(b) < remaining {
+			remaining = len(b)
+		}
+		r.body.Write(b[:remaining])
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *ResponseRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *ResponseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := r.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+func (r *ResponseRecorder) Push(target string, opts *http.PushOptions) error {
+	if p, ok := r.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap allows http.ResponseController to access the underlying ResponseWriter
+func (r *ResponseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,349 @@
+package chatbase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Detector finds personal data in text
+type Detector struct {
+	// Name identifies the kind of data, it is used as label by Mask and Hash
+	Name string
+	// Find returns the start and end index of each match in text
+	Find func(text string) [][]int
+}
+
+// RegexpDetector returns a Detector using the matches of the given expression
+func RegexpDetector(name string, re *regexp.Regexp) Detector {
+	return Detector{
+		Name: name,
+		Find: func(text string) [][]int {
+			return re.FindAllStringIndex(text, -1)
+		},
+	}
+}
+
+// validatedDetector returns a Detector using the matches of the given
+// expression that are accepted by valid
+func validatedDetector(name string, re *regexp.Regexp, valid func(match string) bool) Detector {
+	return Detector{
+		Name: name,
+		Find: func(text string) [][]int {
+			var matches [][]int
+			for _, loc := range re.FindAllStringIndex(text, -1) {
+				if valid(text[loc[0]:loc[1]]) {
+					matches = append(matches, loc)
+				}
+			}
+			return matches
+		},
+	}
+}
+
+// Built-in detectors for common kinds of personal data. Matches are found
+// using heuristics, so they might not catch every occurrence.
+var (
+	EmailDetector = RegexpDetector("EMAIL",
+		regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`))
+	CreditCardDetector = validatedDetector("CREDIT_CARD",
+		regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), luhn)
+	IBANDetector = validatedDetector("IBAN",
+		regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`), validIBAN)
+	IPDetector = validatedDetector("IP",
+		regexp.MustCompile(`(?i)\b(?:\d{1,3}\.){3}\d{1,3}\b|(?:[0-9a-f]{0,4}:){2,7}[0-9a-f]{0,4}`), validIP)
+	PhoneDetector = validatedDetector("PHONE",
+		regexp.MustCompile(`(?:\+|\(|\b)\d[\d ().-]{5,}\d\b`), validPhone)
+)
+
+// DefaultDetectors are used by redactors that have no detectors configured.
+// Detectors listed first take precedence when matches overlap
+var DefaultDetectors = []Detector{
+	EmailDetector,
+	CreditCardDetector,
+	IBANDetector,
+	IPDetector,
+	PhoneDetector,
+}
+
+// Strategy returns the replacement for a match found by the given detector
+type Strategy func(d Detector, match string) string
+
+// Mask replaces matches with the name of their detector, e.g. "[EMAIL]"
+func Mask() Strategy {
+	return func(d Detector, match string) string {
+		return "[" + d.Name + "]"
+	}
+}
+
+// Hash replaces matches with a keyed hash, e.g. "[EMAIL:1a2b3c4d5e6f7a8b]",
+// so identical values can still be correlated without being revealed
+func Hash(key []byte) Strategy {
+	return func(d Detector, match string) string {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(match))
+		return "[" + d.Name + ":" + hex.EncodeToString(mac.Sum(nil))[:16] + "]"
+	}
+}
+
+// Redactor removes personal data from payloads before they are submitted.
+// It is applied to the text of messages, the string properties of events
+// and all "text" fields of Facebook payloads
+type Redactor struct {
+	// Detectors are used for finding personal data, DefaultDetectors
+	// are used when empty
+	Detectors []Detector
+	// Strategy is used for replacing matches, Mask is used when nil
+	Strategy Strategy
+}
+
+// NewRedactor returns a new Redactor using the given strategy and detectors
+func NewRedactor(strategy Strategy, detectors ...Detector) *Redactor {
+	return &Redactor{
+		Detectors: detectors,
+		Strategy:  strategy,
+	}
+}
+
+// WithRedactor redacts all payloads created by the client before they are
+// submitted. The redactor is added to the client's interceptors
+func WithRedactor(r *Redactor) Option {
+	return WithInterceptors(r.Intercept)
+}
+
+// Redact replaces all personal data in text
+func (r *Redactor) Redact(text string) string {
+	if text == "" {
+		return text
+	}
+	detectors := r.Detectors
+	if len(detectors) == 0 {
+		detectors = DefaultDetectors
+	}
+	strategy := r.Strategy
+	if strategy == nil {
+		strategy = Mask()
+	}
+
+	type match struct {
+		start, end int
+		detector   Detector
+	}
+	var matches []match
+	for _, d := range detectors {
+	candidates:
+		for _, loc := range d.Find(text) {
+			for _, m := range matches {
+				if loc[0] < m.end && m.start < loc[1] {
+					continue candidates
+				}
+			}
+			matches = append(matches, match{loc[0], loc[1], d})
+		}
+	}
+	if len(matches) == 0 {
+		return text
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].start < matches[j].start
+	})
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(text[last:m.start])
+		b.WriteString(strategy(m.detector, text[m.start:m.end]))
+		last = m.end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// Intercept is an Interceptor replacing the request's payload with a
+// redacted copy. The caller's payload is not modified
+func (r *Redactor) Intercept(ctx context.Context, req *APIRequest, next APICall) (*APIResponse, error) {
+	payload, err := r.redactPayload(req.Payload)
+	if err != nil {
+		return nil, err
+	}
+	req.Payload = payload
+	return next(ctx, req)
+}
+
+func (r *Redactor) redactPayload(v interface{}) (interface{}, error) {
+	return transform{
+		message:         r.redactMessage,
+		event:           r.redactEvent,
+		facebookMessage: r.redactFacebookMessage,
+		requestResponse: r.redactFacebookRequestResponse,
+	}.apply(v)
+}
+
+func (r *Redactor) redactMessage(m Message) Message {
+	m.Message = r.Redact(m.Message)
+	return m
+}
+
+func (r *Redactor) redactEvent(e Event) Event {
+	properties := make([]EventProperty, len(e.Properties))
+	for i, p := range e.Properties {
+		p.StringValue = r.Redact(p.StringValue)
+		properties[i] = p
+	}
+	if e.Properties != nil {
+		e.Properties = properties
+	}
+	return e
+}
+
+func (r *Redactor) redactFacebookMessage(f FacebookMessage) (FacebookMessage, error) {
+	payload, err := r.redactJSON(f.Payload)
+	f.Payload = payload
+	return f, err
+}
+
+func (r *Redactor) redactFacebookRequestResponse(f FacebookRequestResponse) (FacebookRequestResponse, error) {
+	request, requestErr := r.redactJSON(f.Request)
+	if requestErr != nil {
+		return f, requestErr
+	}
+	response, responseErr := r.redactJSON(f.Response)
+	if responseErr != nil {
+		return f, responseErr
+	}
+	f.Request, f.Response = request, response
+	return f, nil
+}
+
+// redactJSON returns a generic copy of v where all "text" fields have
+// been redacted
+func (r *Redactor) redactJSON(v interface{}) (interface{}, error) {
+	generic, err := genericJSON(v)
+	if err != nil {
+		return nil, err
+	}
+	return r.redactValue(generic), nil
+}
+
+func (r *Redactor) redactValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if s, ok := child.(string); ok && key == "text" {
+				value[key] = r.Redact(s)
+				continue
+			}
+			value[key] = r.redactValue(child)
+		}
+	case []interface{}:
+		for i, child := range value {
+			value[i] = r.redactValue(child)
+		}
+	}
+	return v
+}
+
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// luhn validates credit card numbers using the Luhn checksum
+func luhn(match string) bool {
+	number := digits(match)
+	if len(number) < 13 || len(number) > 19 {
+		return false
+	}
+	sum := 0
+	for i := range number {
+		d := int(number[len(number)-1-i] - '0')
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// validIBAN checks the ISO 13616 checksum of an IBAN
+func validIBAN(match string) bool {
+	iban := strings.ReplaceAll(match, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+	rearranged := iban[4:] + iban[:4]
+	var numeric strings.Builder
+	for _, r := range rearranged {
+		switch {
+		case r >= '0' && r <= '9':
+			numeric.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			numeric.WriteString(strconv.Itoa(int(r-'A') + 10))
+		default:
+			return false
+		}
+	}
+	n, ok := new(big.Int).SetString(numeric.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// validIP accepts IPv4 addresses and IPv6 addresses consisting of at
+// least three groups so text like "e::" is not mistaken for an address
+func validIP(match string) bool {
+	if net.ParseIP(match) == nil {
+		return false
+	}
+	if !strings.Contains(match, ":") {
+		return true
+	}
+	groups := 0
+	for _, group := range strings.Split(match, ":") {
+		if group != "" {
+			groups++
+		}
+	}
+	return groups >= 3
+}
+
+// validPhone accepts numbers of 7 to 15 digits that either start with a
+// country code or are grouped like a phone number, so dates and plain
+// order or ticket numbers are not mistaken for one
+func validPhone(match string) bool {
+	n := len(digits(match))
+	if n < 7 || n > 15 {
+		return false
+	}
+	if strings.HasPrefix(match, "+") || strings.Contains(match, ")") {
+		return true
+	}
+	groups := strings.FieldsFunc(match, func(r rune) bool {
+		return r < '0' || r > '9'
+	})
+	return len(groups) >= 2 && !isDate(groups)
+}
+
+// isDate reports whether the given digit groups look like a date such
+// as 2018-01-02 or 02.01.2018
+func isDate(groups []string) bool {
+	if len(groups) != 3 {
+		return false
+	}
+	first, second, last := len(groups[0]), len(groups[1]), len(groups[2])
+	if second > 2 {
+		return false
+	}
+	return (first == 4 && last <= 2) || (first <= 2 && last == 4)
+}
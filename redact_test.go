@@ -0,0 +1,272 @@
+package chatbase
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRedactor_Redact(t *testing.T) {
+	tests := []struct {
+		name     string
+		redactor *Redactor
+		input    string
+		expected string
+	}{
+		{
+			"empty",
+			&Redactor{},
+			"",
+			"",
+		},
+		{
+			"no personal data",
+			&Redactor{},
+			"I would like to order 2 pizzas at 8.30",
+			"I would like to order 2 pizzas at 8.30",
+		},
+		{
+			"email",
+			&Redactor{},
+			"write to jane.doe+bot@example.co.uk please",
+			"write to [EMAIL] please",
+		},
+		{
+			"credit card",
+			&Redactor{},
+			"my card is 4111 1111 1111 1111, thanks",
+			"my card is [CREDIT_CARD], thanks",
+		},
+		{
+			"invalid credit card",
+			&Redactor{},
+			"order 4111-1111-1111-1112",
+			"order 4111-1111-1111-1112",
+		},
+		{
+			"iban",
+			&Redactor{},
+			"send it to DE89 3704 0044 0532 0130 00 or GB82WEST12345698765432",
+			"send it to [IBAN] or [IBAN]",
+		},
+		{
+			"invalid iban",
+			&Redactor{},
+			"reference DE00ABCDEFGHIJKLMN",
+			"reference DE00ABCDEFGHIJKLMN",
+		},
+		{
+			"ip addresses",
+			&Redactor{},
+			"connect to 192.168.0.1 or 2001:db8::ff00:42:8329 but not 999.1.1.1 or e::",
+			"connect to [IP] or [IP] but not 999.1.1.1 or e::",
+		},
+		{
+			"phone",
+			&Redactor{},
+			"call me at +49 (30) 123 456-78 or 555-1234",
+			"call me at [PHONE] or [PHONE]",
+		},
+		{
+			"grouped phone",
+			&Redactor{},
+			"my number is (555) 123-4567 or 030 1234 5678",
+			"my number is [PHONE] or [PHONE]",
+		},
+		{
+			"dates",
+			&Redactor{},
+			"delivered on 2018-01-02 or 02.01.2018",
+			"delivered on 2018-01-02 or 02.01.2018",
+		},
+		{
+			"order and ticket numbers",
+			&Redactor{},
+			"order 1234567 (ticket 987654321012345)",
+			"order 1234567 (ticket 987654321012345)",
+		},
+		{
+			"custom detectors",
+			NewRedactor(nil, RegexpDetector("ORDER", regexp.MustCompile(`#\d+`)), Detector{
+				Name: "NAME",
+				Find: func(text string) [][]int {
+					if i := strings.Index(text, "Jane"); i >= 0 {
+						return [][]int{{i, i + 4}}
+					}
+					return nil
+				},
+			}),
+			"Jane asked about #1234 via jane@example.com",
+			"[NAME] asked about [ORDER] via jane@example.com",
+		},
+		{
+			"overlapping matches",
+			NewRedactor(nil, RegexpDetector("FIRST", regexp.MustCompile(`abc`)), RegexpDetector("SECOND", regexp.MustCompile(`bcd`))),
+			"abcd bcd",
+			"[FIRST]d [SECOND]",
+		},
+		{
+			"custom strategy",
+			NewRedactor(func(d Detector, match string) string {
+				return strings.Repeat("*", len(match))
+			}, EmailDetector),
+			"ping a@b.io",
+			"ping ******",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := test.redactor.Redact(test.input); result != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	r := NewRedactor(Hash([]byte("secret")), EmailDetector)
+	first := r.Redact("a@example.com")
+	if !regexp.MustCompile(`^\[EMAIL:[0-9a-f]{16}\]$`).MatchString(first) {
+		t.Fatalf("Unexpected replacement %q", first)
+	}
+	if second := r.Redact("a@example.com"); second != first {
+		t.Errorf("Expected stable hash, got %q and %q", first, second)
+	}
+	if other := r.Redact("b@example.com"); other == first {
+		t.Errorf("Expected different hashes for different values, got %q", other)
+	}
+	if rotated := NewRedactor(Hash([]byte("other")), EmailDetector).Redact("a@example.com"); rotated == first {
+		t.Errorf("Expected hash to depend on key, got %q", rotated)
+	}
+}
+
+func TestRedactor_redactPayload(t *testing.T) {
+	r := &Redactor{}
+	tests := []struct {
+		name     string
+		payload  interface{}
+		expected interface{}
+	}{
+		{
+			"message",
+			&Message{UserID: "user", Message: "mail me at a@b.io"},
+			&Message{UserID: "user", Message: "mail me at [EMAIL]"},
+		},
+		{
+			"messages",
+			&Messages{{Message: "a@b.io"}, {Message: "hi"}},
+			&Messages{{Message: "[EMAIL]"}, {Message: "hi"}},
+		},
+		{
+			"event",
+			&Event{Intent: "signup", Properties: []EventProperty{
+				{Name: "email", StringValue: "a@b.io"},
+				{Name: "age", IntegerValue: 42},
+			}},
+			&Event{Intent: "signup", Properties: []EventProperty{
+				{Name: "email", StringValue: "[EMAIL]"},
+				{Name: "age", IntegerValue: 42},
+			}},
+		},
+		{
+			"events",
+			&Events{{Intent: "signup", Properties: []EventProperty{{Name: "email", StringValue: "a@b.io"}}}},
+			&Events{{Intent: "signup", Properties: []EventProperty{{Name: "email", StringValue: "[EMAIL]"}}}},
+		},
+		{
+			"facebook message",
+			&FacebookMessage{Payload: map[string]interface{}{
+				"sender":  map[string]interface{}{"id": "123"},
+				"message": map[string]interface{}{"text": "a@b.io", "seq": 12},
+			}},
+			&FacebookMessage{Payload: map[string]interface{}{
+				"sender":  map[string]interface{}{"id": "123"},
+				"message": map[string]interface{}{"text": "[EMAIL]", "seq": json.Number("12")},
+			}},
+		},
+		{
+			"facebook request response",
+			&FacebookRequestResponse{
+				Request:  json.RawMessage(`{"message":{"text":"is a@b.io yours?"}}`),
+				Response: json.RawMessage(`{"message_id":"mid"}`),
+			},
+			&FacebookRequestResponse{
+				Request:  map[string]interface{}{"message": map[string]interface{}{"text": "is [EMAIL] yours?"}},
+				Response: map[string]interface{}{"message_id": "mid"},
+			},
+		},
+		{
+			"facebook request responses",
+			&FacebookRequestResponses{{
+				Request:  map[string]interface{}{"messages": []interface{}{map[string]interface{}{"text": "a@b.io"}}},
+				Response: nil,
+			}},
+			&FacebookRequestResponses{{
+				Request:  map[string]interface{}{"messages": []interface{}{map[string]interface{}{"text": "[EMAIL]"}}},
+				Response: nil,
+			}},
+		},
+		{
+			"unsupported",
+			&Update{Intent: "a@b.io"},
+			&Update{Intent: "a@b.io"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			before, _ := json.Marshal(test.payload)
+			result, err := r.redactPayload(test.payload)
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(test.expected, result) {
+				t.Errorf("Expected %#v, got %#v", test.expected, result)
+			}
+			if after, _ := json.Marshal(test.payload); string(before) != string(after) {
+				t.Errorf("Expected payload to be unchanged, got %s", after)
+			}
+		})
+	}
+
+	t.Run("bad payload", func(t *testing.T) {
+		if _, err := r.redactPayload(&FacebookMessage{Payload: make(chan int)}); err == nil {
+			t.Error("Expected error, got nil")
+		}
+	})
+}
+
+func TestWithRedactor(t *testing.T) {
+	oldEndpoint := messageEndpoint
+	defer func() { messageEndpoint = oldEndpoint }()
+
+	var received Message
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(b, &received)
+		w.Write([]byte(`{"message_id":"123","status":200}`))
+	}))
+	defer ts.Close()
+	messageEndpoint = ts.URL
+
+	var seen string
+	c := New("key", WithRedactor(&Redactor{}), WithInterceptors(func(ctx context.Context, req *APIRequest, next APICall) (*APIResponse, error) {
+		seen = req.Payload.(*Message).Message
+		return next(ctx, req)
+	}))
+	m := c.UserMessage("user", PlatformWeb).SetMessage("I am at 10.0.0.1")
+	if _, err := m.Submit(); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if received.Message != "I am at [IP]" || seen != "I am at [IP]" {
+		t.Errorf("Unexpected redaction, received %q, seen %q", received.Message, seen)
+	}
+	if m.Message != "I am at 10.0.0.1" {
+		t.Errorf("Expected message to be unchanged, got %q", m.Message)
+	}
+}
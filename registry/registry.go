@@ -0,0 +1,316 @@
+/*
+Package registry maps bot names to Chatbase clients using individual API keys,
+platforms, versions and redaction rules. The configuration is read from YAML
+or JSON files or from environment variables and can be reloaded at any time:
+
+	r := registry.New(chatbase.WithLogger(slog.Default()))
+	if err := r.LoadFile("/etc/bot/chatbase.yaml"); err != nil {
+		// handle error
+	}
+	client, err := r.Lookup("support")
+*/
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnknownBot is returned for bot names that are not configured
+var ErrUnknownBot = errors.New("unknown bot")
+
+// envPrefix is the prefix of environment variables read by LoadEnv
+const envPrefix = "CHATBASE_BOT_"
+
+// Config configures the bots of a Registry
+type Config struct {
+	Bots map[string]BotConfig `json:"bots" yaml:"bots"`
+}
+
+// BotConfig configures the client of a single bot
+type BotConfig struct {
+	APIKey string `json:"api_key" yaml:"api_key"`
+	// Platform is used for payloads created without a platform
+	Platform string `json:"platform,omitempty" yaml:"platform,omitempty"`
+	// Version is used for all payloads created by the bot's client
+	Version   string           `json:"version,omitempty" yaml:"version,omitempty"`
+	Redaction *RedactionConfig `json:"redaction,omitempty" yaml:"redaction,omitempty"`
+}
+
+// RedactionConfig configures the Redactor of a bot
+type RedactionConfig struct {
+	// Detectors are the names of built-in detectors, i.e. "email", "phone",
+	// "credit_card", "iban" and "ip". All of them are used when empty
+	Detectors []string `json:"detectors,omitempty" yaml:"detectors,omitempty"`
+	// Strategy is either "mask" or "hash", defaults to "mask"
+	Strategy string `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+	// HashKey is the key used by the "hash" strategy
+	HashKey string `json:"hash_key,omitempty" yaml:"hash_key,omitempty"`
+}
+
+// detectors maps the names used in configuration to built-in detectors
+var detectors = map[string]chatbase.Detector{
+	"email":       chatbase.EmailDetector,
+	"credit_card": chatbase.CreditCardDetector,
+	"iban":        chatbase.IBANDetector,
+	"ip":          chatbase.IPDetector,
+	"phone":       chatbase.PhoneDetector,
+}
+
+// Registry maps bot names to clients. It can be reloaded at any time,
+// payloads created before keep using the configuration of their client
+type Registry struct {
+	mu      sync.RWMutex
+	options []chatbase.Option
+	clients map[string]*chatbase.Client
+	// modified is the modification time of the file loaded last
+	modified time.Time
+}
+
+// New returns a new empty Registry. The given options are
+// applied to the clients of all bots
+func New(options ...chatbase.Option) *Registry {
+	return &Registry{
+		options: options,
+		clients: map[string]*chatbase.Client{},
+	}
+}
+
+// Lookup returns the client of the bot with the given name, or an error
+// wrapping ErrUnknownBot if no such bot is configured
+func (r *Registry) Lookup(name string) (*chatbase.Client, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.clients[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownBot, name)
+	}
+	return c, nil
+}
+
+// Bot returns the client of the bot with the given name for chaining calls.
+// Unknown names never fall back to another bot: all submissions of the
+// returned client fail with an error wrapping ErrUnknownBot
+func (r *Registry) Bot(name string) *chatbase.Client {
+	c, err := r.Lookup(name)
+	if err == nil {
+		return c
+	}
+	reject := func(ctx context.Context, req *chatbase.APIRequest, next chatbase.APICall) (*chatbase.APIResponse, error) {
+		return nil, err
+	}
+	return chatbase.New("", append(append([]chatbase.Option{}, r.options...), chatbase.WithInterceptors(reject))...)
+}
+
+// Names returns the sorted names of all configured bots
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.clients))
+	for name := range r.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Load replaces all bots with the given configuration. The registry is
+// left unchanged when the configuration is invalid, e.g. when a bot has
+// no API key
+func (r *Registry) Load(cfg Config) error {
+	clients := map[string]*chatbase.Client{}
+	for name, bot := range cfg.Bots {
+		c, err := r.newClient(bot)
+		if err != nil {
+			return fmt.Errorf("bot %q: %v", name, err)
+		}
+		clients[name] = c
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clients = clients
+	return nil
+}
+
+// LoadFile replaces all bots with the configuration read from the file at
+// the given path. Files ending in ".yaml" or ".yml" are parsed as YAML,
+// all others as JSON
+func (r *Registry) LoadFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	cfg, err := readConfig(path)
+	if err != nil {
+		return err
+	}
+	if err := r.Load(cfg); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.modified = info.ModTime()
+	return nil
+}
+
+// LoadEnv replaces all bots with the configuration read from environment
+// variables like CHATBASE_BOT_SUPPORT_API_KEY, CHATBASE_BOT_SUPPORT_PLATFORM,
+// CHATBASE_BOT_SUPPORT_VERSION, CHATBASE_BOT_SUPPORT_REDACT_DETECTORS
+// (comma separated), CHATBASE_BOT_SUPPORT_REDACT_STRATEGY and
+// CHATBASE_BOT_SUPPORT_REDACT_HASH_KEY, which configure the bot "support"
+func (r *Registry) LoadEnv() error {
+	return r.Load(envConfig(os.Environ()))
+}
+
+// Watch reloads the file at the given path whenever its modification time
+// differs from the file loaded last until the context is canceled. Errors
+// are passed to onError if given, the previous configuration is kept in
+// this case
+func (r *Registry) Watch(ctx context.Context, path string, interval time.Duration, onError func(error)) {
+	// failed is the modification time of the last file that could not
+	// be loaded so errors are only reported once per change
+	var failed time.Time
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(path)
+			if err == nil {
+				r.mu.RLock()
+				unchanged := info.ModTime().Equal(r.modified)
+				r.mu.RUnlock()
+				if unchanged || info.ModTime().Equal(failed) {
+					continue
+				}
+				if err = r.LoadFile(path); err != nil {
+					failed = info.ModTime()
+				}
+			}
+			if err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+func (r *Registry) newClient(bot BotConfig) (*chatbase.Client, error) {
+	if bot.APIKey == "" {
+		return nil, errors.New("missing api key")
+	}
+	options := append([]chatbase.Option{}, r.options...)
+	if bot.Platform != "" {
+		options = append(options, chatbase.WithPlatform(bot.Platform))
+	}
+	if bot.Version != "" {
+		options = append(options, chatbase.WithVersion(bot.Version))
+	}
+	if bot.Redaction != nil {
+		redactor, err := bot.Redaction.redactor()
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, chatbase.WithRedactor(redactor))
+	}
+	return chatbase.New(bot.APIKey, options...), nil
+}
+
+func (c *RedactionConfig) redactor() (*chatbase.Redactor, error) {
+	r := &chatbase.Redactor{}
+	for _, name := range c.Detectors {
+		d, ok := detectors[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown detector %q", name)
+		}
+		r.Detectors = append(r.Detectors, d)
+	}
+	switch c.Strategy {
+	case "", "mask":
+		r.Strategy = chatbase.Mask()
+	case "hash":
+		if c.HashKey == "" {
+			return nil, fmt.Errorf("hash strategy requires a hash key")
+		}
+		r.Strategy = chatbase.Hash([]byte(c.HashKey))
+	default:
+		return nil, fmt.Errorf("unknown strategy %q", c.Strategy)
+	}
+	return r, nil
+}
+
+func readConfig(path string) (Config, error) {
+	var cfg Config
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &cfg)
+	default:
+		err = json.Unmarshal(b, &cfg)
+	}
+	return cfg, err
+}
+
+// envConfig reads the configuration of all bots from the given
+// environment in "KEY=value" form
+func envConfig(environ []string) Config {
+	suffixes := []string{"_API_KEY", "_PLATFORM", "_VERSION", "_REDACT_DETECTORS", "_REDACT_STRATEGY", "_REDACT_HASH_KEY"}
+	cfg := Config{Bots: map[string]BotConfig{}}
+	for _, variable := range environ {
+		pair := strings.SplitN(variable, "=", 2)
+		if len(pair) != 2 || !strings.HasPrefix(pair[0], envPrefix) {
+			continue
+		}
+		key, value := strings.TrimPrefix(pair[0], envPrefix), pair[1]
+		for _, suffix := range suffixes {
+			if !strings.HasSuffix(key, suffix) || len(key) == len(suffix) {
+				continue
+			}
+			name := strings.ToLower(strings.TrimSuffix(key, suffix))
+			bot := cfg.Bots[name]
+			redaction := func() *RedactionConfig {
+				if bot.Redaction == nil {
+					bot.Redaction = &RedactionConfig{}
+				}
+				return bot.Redaction
+			}
+			switch suffix {
+			case "_API_KEY":
+				bot.APIKey = value
+			case "_PLATFORM":
+				bot.Platform = value
+			case "_VERSION":
+				bot.Version = value
+			case "_REDACT_DETECTORS":
+				for _, d := range strings.Split(value, ",") {
+					if d = strings.TrimSpace(d); d != "" {
+						redaction().Detectors = append(redaction().Detectors, d)
+					}
+				}
+			case "_REDACT_STRATEGY":
+				redaction().Strategy = value
+			case "_REDACT_HASH_KEY":
+				redaction().HashKey = value
+			}
+			cfg.Bots[name] = bot
+			break
+		}
+	}
+	return cfg
+}
@@ -0,0 +1,263 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// captureMessages makes all clients submit to a fake API and returns
+// the messages received by it
+func captureMessages(t *testing.T) <-chan chatbase.Message {
+	received := make(chan chatbase.Message, 10)
+	chatbase.SetAPITransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		var m chatbase.Message
+		json.NewDecoder(req.Body).Decode(&m)
+		received <- m
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(`{"message_id":"1","status":200}`))}, nil
+	}))
+	t.Cleanup(func() { chatbase.SetAPITransport(nil) })
+	return received
+}
+
+func TestRegistry_LoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chatbase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"bots.yaml": `
+bots:
+  support:
+    api_key: support-key
+    platform: Web
+    redaction:
+      detectors: [email]
+      strategy: hash
+      hash_key: secret
+  sales:
+    api_key: sales-key
+`,
+		"bots.json": `{"bots":{"support":{"api_key":"support-key","platform":"Web","redaction":{"detectors":["email"],"strategy":"hash","hash_key":"secret"}},"sales":{"api_key":"sales-key"}}}`,
+	}
+	received := captureMessages(t)
+	for name, contents := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			ioutil.WriteFile(path, []byte(contents), 0600)
+			r := New()
+			if err := r.LoadFile(path); err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if names := r.Names(); !reflect.DeepEqual([]string{"sales", "support"}, names) {
+				t.Errorf("Unexpected names %v", names)
+			}
+			if key := r.Bot("support").String(); key != "support-key" {
+				t.Errorf("Unexpected key %v", key)
+			}
+			r.Bot("sales").UserMessage("user", "").SetMessage("a@b.io").Submit()
+			if m := <-received; m.Platform != "" || m.Message != "a@b.io" {
+				t.Errorf("Expected message without defaults, got %#v", m)
+			}
+			r.Bot("support").UserMessage("user", "").SetMessage("a@b.io").Submit()
+			if m := <-received; m.Platform != chatbase.PlatformWeb || m.Message == "a@b.io" {
+				t.Errorf("Expected default platform and redacted message, got %#v", m)
+			}
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		r := New()
+		r.Load(Config{Bots: map[string]BotConfig{"support": {APIKey: "key"}}})
+		invalid := map[string]string{
+			"missing.json":  "",
+			"invalid.json":  "{",
+			"api_key.json":  `{"bots":{"support":{"platform":"Web"}}}`,
+			"detector.json": `{"bots":{"support":{"api_key":"other","redaction":{"detectors":["zip"]}}}}`,
+			"strategy.json": `{"bots":{"support":{"api_key":"other","redaction":{"strategy":"shred"}}}}`,
+			"hash.json":     `{"bots":{"support":{"api_key":"other","redaction":{"strategy":"hash"}}}}`,
+		}
+		for name, contents := range invalid {
+			path := filepath.Join(dir, name)
+			if contents != "" {
+				ioutil.WriteFile(path, []byte(contents), 0600)
+			}
+			if err := r.LoadFile(path); err == nil {
+				t.Errorf("Expected error loading %s, got nil", name)
+			}
+		}
+		if key := r.Bot("support").String(); key != "key" {
+			t.Errorf("Expected previous configuration to be kept, got %v", key)
+		}
+	})
+}
+
+func TestRegistry_Lookup(t *testing.T) {
+	received := captureMessages(t)
+	r := New()
+	r.Load(Config{Bots: map[string]BotConfig{"default": {APIKey: "fallback"}, "support": {APIKey: "support-key"}}})
+	if c, err := r.Lookup("support"); err != nil || c.String() != "support-key" {
+		t.Errorf("Unexpected result %v %v", c, err)
+	}
+	if c, err := r.Lookup("unknown"); c != nil || !errors.Is(err, ErrUnknownBot) {
+		t.Errorf("Expected ErrUnknownBot, got %v %v", c, err)
+	}
+	if _, err := r.Bot("unknown").UserMessage("user", chatbase.PlatformWeb).Submit(); !errors.Is(err, ErrUnknownBot) {
+		t.Errorf("Expected submission of unknown bot to fail, got %v", err)
+	}
+	select {
+	case m := <-received:
+		t.Errorf("Unexpected submission %#v", m)
+	default:
+	}
+}
+
+func TestEnvConfig(t *testing.T) {
+	cfg := envConfig([]string{
+		"CHATBASE_BOT_SUPPORT_API_KEY=support-key",
+		"CHATBASE_BOT_SUPPORT_PLATFORM=Web",
+		"CHATBASE_BOT_SUPPORT_VERSION=1.2",
+		"CHATBASE_BOT_SUPPORT_REDACT_DETECTORS=email, phone",
+		"CHATBASE_BOT_SUPPORT_REDACT_STRATEGY=hash",
+		"CHATBASE_BOT_SUPPORT_REDACT_HASH_KEY=secret",
+		"CHATBASE_BOT_SALES_EU_API_KEY=sales=key",
+		"CHATBASE_BOT_API_KEY=ignored",
+		"CHATBASE_BOT_SALES_EU_UNKNOWN=ignored",
+		"HOME=/root",
+	})
+	expected := Config{Bots: map[string]BotConfig{
+		"support": {
+			APIKey:   "support-key",
+			Platform: "Web",
+			Version:  "1.2",
+			Redaction: &RedactionConfig{
+				Detectors: []string{"email", "phone"},
+				Strategy:  "hash",
+				HashKey:   "secret",
+			},
+		},
+		"sales_eu": {APIKey: "sales=key"},
+	}}
+	if !reflect.DeepEqual(expected, cfg) {
+		t.Errorf("Expected %#v, got %#v", expected, cfg)
+	}
+}
+
+func TestRegistry_LoadEnv(t *testing.T) {
+	os.Setenv("CHATBASE_BOT_TESTBOT_API_KEY", "env-key")
+	defer os.Unsetenv("CHATBASE_BOT_TESTBOT_API_KEY")
+	r := New()
+	if err := r.LoadEnv(); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if key := r.Bot("testbot").String(); key != "env-key" {
+		t.Errorf("Unexpected key %v", key)
+	}
+	t.Run("missing api key", func(t *testing.T) {
+		os.Setenv("CHATBASE_BOT_OTHERBOT_PLATFORM", "Web")
+		defer os.Unsetenv("CHATBASE_BOT_OTHERBOT_PLATFORM")
+		if err := r.LoadEnv(); err == nil {
+			t.Error("Expected error, got nil")
+		}
+		if names := r.Names(); len(names) != 1 || names[0] != "testbot" {
+			t.Errorf("Expected previous configuration to be kept, got %v", names)
+		}
+	})
+}
+
+func TestRegistry_Watch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chatbase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "bots.json")
+	ioutil.WriteFile(path, []byte(`{"bots":{"support":{"api_key":"first"}}}`), 0600)
+
+	r := New()
+	if err := r.LoadFile(path); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 10)
+	go r.Watch(ctx, path, 5*time.Millisecond, func(err error) { errs <- err })
+
+	later := time.Now().Add(time.Second)
+	ioutil.WriteFile(path, []byte(`{"bots":{"support":{"api_key":"second"}}}`), 0600)
+	os.Chtimes(path, later, later)
+	waitFor(t, func() bool { return r.Bot("support").String() == "second" })
+
+	later = later.Add(time.Second)
+	ioutil.WriteFile(path, []byte(`{`), 0600)
+	os.Chtimes(path, later, later)
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("Expected error, got nil")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected error to be reported")
+	}
+	if key := r.Bot("support").String(); key != "second" {
+		t.Errorf("Expected previous configuration to be kept, got %v", key)
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("Condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRegistry_Submit(t *testing.T) {
+	messages := captureMessages(t)
+	r := New()
+	r.Load(Config{Bots: map[string]BotConfig{
+		"support": {
+			APIKey:    "support-key",
+			Platform:  chatbase.PlatformWeb,
+			Version:   "1.2",
+			Redaction: &RedactionConfig{Detectors: []string{"email"}},
+		},
+	}})
+	if _, err := r.Bot("support").UserMessage("user", "").SetMessage("a@b.io").Submit(); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	received := <-messages
+	expected := chatbase.Message{
+		APIKey:    "support-key",
+		Type:      chatbase.UserType,
+		UserID:    "user",
+		Platform:  chatbase.PlatformWeb,
+		Version:   "1.2",
+		Message:   "[EMAIL]",
+		TimeStamp: received.TimeStamp,
+	}
+	if !reflect.DeepEqual(expected, received) {
+		t.Errorf("Expected %#v, got %#v", expected, received)
+	}
+}
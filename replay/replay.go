@@ -0,0 +1,278 @@
+/*
+Package replay resubmits archived Chatbase traffic, e.g. for load testing
+a staging API key.
+
+Archived traffic is read from newline delimited JSON where each line is
+either a serialized Message or Event:
+
+	items, err := replay.Decode(file)
+	if err != nil {
+		// handle error
+	}
+	r := &replay.Replayer{
+		Client:          chatbase.New("STAGING-API-KEY"),
+		ShiftTimeStamps: true,
+		Speed:           10,
+	}
+	if err := r.Replay(ctx, items); err != nil {
+		// handle error
+	}
+*/
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+// Item is a single archived payload, either a Message or an Event
+type Item struct {
+	Message *chatbase.Message
+	Event   *chatbase.Event
+}
+
+// TimeStamp returns the original time stamp of the item. Events that
+// have been archived without a time stamp return 0
+func (i *Item) TimeStamp() int64 {
+	if i.Message != nil {
+		return i.Message.TimeStamp
+	}
+	if i.Event != nil {
+		return i.Event.TimeStamp
+	}
+	return 0
+}
+
+// MarshalJSON serializes the wrapped payload
+func (i Item) MarshalJSON() ([]byte, error) {
+	if i.Message != nil {
+		return json.Marshal(i.Message)
+	}
+	if i.Event != nil {
+		return json.Marshal(i.Event)
+	}
+	return nil, errors.New("cannot marshal empty item")
+}
+
+// UnmarshalJSON decides whether the given data is a Message or an Event
+// by checking for the presence of the message "type" field
+func (i *Item) UnmarshalJSON(b []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+	if _, ok := fields["type"]; ok {
+		i.Message = &chatbase.Message{}
+		return json.Unmarshal(b, i.Message)
+	}
+	i.Event = &chatbase.Event{}
+	return json.Unmarshal(b, i.Event)
+}
+
+// Decode reads newline delimited JSON archives. Blank lines are skipped.
+func Decode(r io.Reader) ([]Item, error) {
+	var items []Item
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		b := bytes.TrimSpace(scanner.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		var item Item
+		if err := json.Unmarshal(b, &item); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Replayer resubmits archived items
+type Replayer struct {
+	// Client is the client the items are replayed against. When set, the
+	// items are recreated using the client, so they are submitted with its
+	// key, defaults and options instead of the archived API key
+	Client *chatbase.Client
+	// UserIDs can be used for remapping the archived user ids
+	UserIDs func(string) string
+	// ShiftTimeStamps moves all time stamps so the first item is stamped
+	// with the time the replay started while keeping their relative
+	// distance (scaled by Speed)
+	ShiftTimeStamps bool
+	// Speed paces the submissions relative to the original traffic. 1 replays
+	// at the original speed, 2 twice as fast. 0 submits without pausing
+	Speed float64
+	// OnError is called for each item that could not be submitted. If it is
+	// nil, the replay stops at the first error
+	OnError func(Item, error)
+}
+
+// Replay submits the given items in order while considering the
+// given context's deadline. Items are expected to be sorted by their
+// time stamp. The original items are not modified.
+func (r *Replayer) Replay(ctx context.Context, items []Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+	start := time.Now()
+	first := firstTimeStamp(items)
+	for index, item := range items {
+		elapsed := r.scale(item.TimeStamp() - first)
+		if r.Speed > 0 && item.TimeStamp() != 0 {
+			wait := time.Until(start.Add(time.Duration(elapsed) * time.Millisecond))
+			if err := sleep(ctx, wait); err != nil {
+				return err
+			}
+		}
+		rewritten := r.rewrite(item, start.UnixNano()/1e6+elapsed)
+		if err := submit(ctx, rewritten); err != nil {
+			err = fmt.Errorf("item %d: %v", index, err)
+			if r.OnError == nil {
+				return err
+			}
+			r.OnError(item, err)
+		}
+	}
+	return nil
+}
+
+func (r *Replayer) scale(millis int64) int64 {
+	if r.Speed <= 0 {
+		return millis
+	}
+	return int64(float64(millis) / r.Speed)
+}
+
+// rewrite returns a copy of the item. When a client is set, the copy is
+// created using it so the client's key, defaults and options apply
+func (r *Replayer) rewrite(item Item, shifted int64) Item {
+	if item.Message != nil {
+		m := r.message(item.Message)
+		if r.ShiftTimeStamps {
+			m.TimeStamp = shifted
+		}
+		return Item{Message: m}
+	}
+	if item.Event == nil {
+		return item
+	}
+	e := r.event(item.Event)
+	if r.ShiftTimeStamps && e.TimeStamp != 0 {
+		e.TimeStamp = shifted
+	}
+	return Item{Event: e}
+}
+
+func (r *Replayer) userID(archived string) string {
+	if r.UserIDs != nil {
+		return r.UserIDs(archived)
+	}
+	return archived
+}
+
+func (r *Replayer) message(archived *chatbase.Message) *chatbase.Message {
+	if r.Client == nil {
+		m := *archived
+		m.UserID = r.userID(m.UserID)
+		return &m
+	}
+	m := r.Client.Message(archived.Type, r.userID(archived.UserID), archived.Platform).
+		SetMessage(archived.Message).
+		SetIntent(archived.Intent).
+		SetNotHandled(archived.NotHandled).
+		SetFeedback(archived.Feedback).
+		SetTimeStamp(archived.TimeStamp)
+	if archived.Version != "" {
+		m.SetVersion(archived.Version)
+	}
+	if archived.SessionID != "" {
+		m.SetSessionID(archived.SessionID)
+	}
+	return m
+}
+
+func (r *Replayer) event(archived *chatbase.Event) *chatbase.Event {
+	if r.Client == nil {
+		e := *archived
+		e.UserID = r.userID(e.UserID)
+		return &e
+	}
+	e := r.Client.Event(r.userID(archived.UserID), archived.Intent).
+		SetTimeStamp(archived.TimeStamp)
+	if archived.Platform != "" {
+		e.SetPlatform(archived.Platform)
+	}
+	if archived.Version != "" {
+		e.SetVersion(archived.Version)
+	}
+	for _, p := range archived.Properties {
+		e.AddProperty(p.Name, propertyValue(p))
+	}
+	return e
+}
+
+// propertyValue returns the value of an archived property. Properties
+// holding a zero value serialize the same regardless of their type
+func propertyValue(p chatbase.EventProperty) interface{} {
+	switch {
+	case p.StringValue != "":
+		return p.StringValue
+	case p.IntegerValue != 0:
+		return p.IntegerValue
+	case p.FloatValue != 0:
+		return p.FloatValue
+	}
+	return p.BoolValue
+}
+
+func firstTimeStamp(items []Item) int64 {
+	for _, item := range items {
+		if ts := item.TimeStamp(); ts != 0 {
+			return ts
+		}
+	}
+	return 0
+}
+
+func submit(ctx context.Context, item Item) error {
+	if item.Message != nil {
+		res, err := item.Message.SubmitWithContext(ctx)
+		if err != nil {
+			return err
+		}
+		if !res.Status.OK() {
+			return fmt.Errorf("message was rejected: %s", res.Reason)
+		}
+		return nil
+	}
+	if item.Event != nil {
+		return item.Event.SubmitWithContext(ctx)
+	}
+	return errors.New("cannot submit empty item")
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,216 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (r roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r(req)
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expectError bool
+		expected    []Item
+	}{
+		{
+			"default",
+			`{"api_key":"a","type":"user","user_id":"u","time_stamp":1000,"platform":"Web","message":"hi"}
+
+{"api_key":"a","user_id":"u","intent":"clicked","timestamp_millis":2000,"properties":null}
+`,
+			false,
+			[]Item{
+				{Message: &chatbase.Message{APIKey: "a", Type: chatbase.UserType, UserID: "u", TimeStamp: 1000, Platform: "Web", Message: "hi"}},
+				{Event: &chatbase.Event{APIKey: "a", UserID: "u", Intent: "clicked", TimeStamp: 2000}},
+			},
+		},
+		{
+			"bad line",
+			"{\"type\":\"user\"}\n{{{\n",
+			true,
+			nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := Decode(strings.NewReader(test.input))
+			if test.expectError != (err != nil) {
+				t.Errorf("Unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(test.expected, result) {
+				t.Errorf("Expected %#v, got %#v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestItem_MarshalJSON(t *testing.T) {
+	t.Run("roundtrip", func(t *testing.T) {
+		item := Item{Message: &chatbase.Message{APIKey: "a", Type: chatbase.AgentType, UserID: "u", TimeStamp: 1, Platform: "Web"}}
+		b, err := json.Marshal(item)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		var result Item
+		if err := json.Unmarshal(b, &result); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if !reflect.DeepEqual(item, result) {
+			t.Errorf("Expected %#v, got %#v", item, result)
+		}
+	})
+	t.Run("empty", func(t *testing.T) {
+		if _, err := json.Marshal(Item{}); err == nil {
+			t.Error("Expected error, got nil")
+		}
+	})
+}
+
+func TestReplayer_Replay(t *testing.T) {
+	defer chatbase.SetAPITransport(nil)
+	var mu sync.Mutex
+	var received []map[string]interface{}
+	var times []time.Time
+	chatbase.SetAPITransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		received = append(received, payload)
+		times = append(times, time.Now())
+		mu.Unlock()
+		status := "200"
+		if payload["user_id"] == "replayed-reject" {
+			status = "400"
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"status":` + status + `}`)),
+		}, nil
+	}))
+
+	items := []Item{
+		{Message: &chatbase.Message{APIKey: "prod", Type: chatbase.UserType, UserID: "u", TimeStamp: 10000, Platform: "Web"}},
+		{Event: &chatbase.Event{APIKey: "prod", UserID: "u", Intent: "clicked", TimeStamp: 11000}},
+		{Message: &chatbase.Message{APIKey: "prod", Type: chatbase.AgentType, UserID: "u", TimeStamp: 14000, Platform: "Web"}},
+	}
+
+	t.Run("default", func(t *testing.T) {
+		received, times = nil, nil
+		r := &Replayer{
+			Client:          chatbase.New("staging"),
+			UserIDs:         func(id string) string { return "replayed-" + id },
+			ShiftTimeStamps: true,
+			Speed:           10,
+		}
+		start := time.Now()
+		if err := r.Replay(context.Background(), items); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if len(received) != 3 {
+			t.Fatalf("Unexpected number of requests %d", len(received))
+		}
+		for _, payload := range received {
+			if payload["api_key"] != "staging" || payload["user_id"] != "replayed-u" {
+				t.Errorf("Unexpected payload %v", payload)
+			}
+		}
+		if elapsed := times[2].Sub(start); elapsed < 400*time.Millisecond {
+			t.Errorf("Expected submissions to be paced, took %v", elapsed)
+		}
+		first := int64(received[0]["time_stamp"].(float64))
+		if first < start.UnixNano()/1e6 {
+			t.Errorf("Expected shifted time stamp, got %v", first)
+		}
+		if last := int64(received[2]["time_stamp"].(float64)); last-first != 400 {
+			t.Errorf("Expected scaled distance of 400ms, got %v", last-first)
+		}
+		if items[0].Message.APIKey != "prod" {
+			t.Error("Expected original items to be untouched")
+		}
+	})
+	t.Run("client options", func(t *testing.T) {
+		received, times = nil, nil
+		plan, _ := chatbase.NewEventProperty("plan", "pro")
+		intercepted := 0
+		r := &Replayer{
+			Client: chatbase.New("staging",
+				chatbase.WithVersion("2.0.0"),
+				chatbase.WithEventProperties(plan),
+				chatbase.WithInterceptors(func(ctx context.Context, req *chatbase.APIRequest, next chatbase.APICall) (*chatbase.APIResponse, error) {
+					intercepted++
+					return next(ctx, req)
+				}),
+			),
+		}
+		archived := []Item{
+			{Message: &chatbase.Message{APIKey: "prod", Type: chatbase.UserType, UserID: "u", TimeStamp: 10000, Platform: "Web", Message: "hi", SessionID: "s"}},
+			{Event: &chatbase.Event{APIKey: "prod", UserID: "u", Intent: "clicked", Version: "1.0.0", Properties: []chatbase.EventProperty{{Name: "plan", StringValue: "free"}, {Name: "seats", IntegerValue: 3}}}},
+		}
+		if err := r.Replay(context.Background(), archived); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if intercepted != 2 || len(received) != 2 {
+			t.Fatalf("Expected items to be submitted using the client, got %d requests and %d intercepted", len(received), intercepted)
+		}
+		if m := received[0]; m["api_key"] != "staging" || m["version"] != "2.0.0" || m["message"] != "hi" || m["session_id"] != "s" || m["time_stamp"] != float64(10000) {
+			t.Errorf("Unexpected message %v", m)
+		}
+		var event chatbase.Event
+		b, _ := json.Marshal(received[1])
+		json.Unmarshal(b, &event)
+		expected := []chatbase.EventProperty{{Name: "plan", StringValue: "free"}, {Name: "seats", IntegerValue: 3}}
+		if event.APIKey != "staging" || event.Version != "1.0.0" || !reflect.DeepEqual(expected, event.Properties) {
+			t.Errorf("Unexpected event %#v", event)
+		}
+	})
+	t.Run("error", func(t *testing.T) {
+		received, times = nil, nil
+		r := &Replayer{
+			UserIDs: func(id string) string { return "replayed-reject" },
+		}
+		if err := r.Replay(context.Background(), items); err == nil {
+			t.Error("Expected error, got nil")
+		}
+		if len(received) != 1 {
+			t.Errorf("Expected replay to stop, got %d requests", len(received))
+		}
+	})
+	t.Run("continue on error", func(t *testing.T) {
+		received, times = nil, nil
+		var errs []error
+		r := &Replayer{
+			UserIDs: func(id string) string { return "replayed-reject" },
+			OnError: func(_ Item, err error) { errs = append(errs, err) },
+		}
+		if err := r.Replay(context.Background(), items); err != nil {
+			t.Errorf("Unexpected error %v", err)
+		}
+		if len(errs) != 2 {
+			t.Errorf("Unexpected errors %v", errs)
+		}
+	})
+	t.Run("cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		r := &Replayer{Speed: 1}
+		if err := r.Replay(ctx, items); !errors.Is(err, context.Canceled) {
+			t.Errorf("Unexpected error %v", err)
+		}
+	})
+}
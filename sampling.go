@@ -0,0 +1,238 @@
+package chatbase
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"strconv"
+	"sync"
+)
+
+// SampleRateProperty is the name of the event property the sample rate
+// is recorded in
+const SampleRateProperty = "sample_rate"
+
+// samplingPipeline is the pipeline sampled out items are reported for
+const samplingPipeline = "sampling"
+
+// Sampler keeps a deterministic share of conversations. Items are kept
+// depending on a hash of their user or session id, so a conversation is
+// either kept or dropped as a whole. The rate applied to each kept item
+// is recorded in the "version" of messages and Facebook payloads and in
+// the SampleRateProperty of events, so counts can be extrapolated
+type Sampler struct {
+	// Rate is the share of conversations that is kept, between 0 and 1
+	Rate float64
+	// BySession samples messages by session id instead of user id.
+	// Messages without session id as well as events and Facebook
+	// payloads are always sampled by user id
+	BySession bool
+	// Intents overrides the rate for items with the given intents. As
+	// the hash of a conversation does not depend on the intent, a
+	// conversation that is kept is also kept for intents with higher rates.
+	// Use SetIntent for changing it once the Sampler is in use
+	Intents map[string]float64
+	// KeepNotHandled keeps all messages that have not been handled
+	KeepNotHandled bool
+	mu             sync.RWMutex
+}
+
+// NewSampler returns a new Sampler keeping the given share of
+// conversations and all messages that have not been handled
+func NewSampler(rate float64) *Sampler {
+	return &Sampler{
+		Rate:           rate,
+		KeepNotHandled: true,
+	}
+}
+
+// WithSampler samples all payloads created by the client before they are
+// submitted. Sampled out items are not submitted but reported as
+// successful responses whose Withheld field is set to "sampling"
+func WithSampler(s *Sampler) Option {
+	return func(c *config) {
+		c.sampler = s
+	}
+}
+
+// SetIntent overrides the rate for items with the given intent. It is
+// safe to call while payloads are being submitted
+func (s *Sampler) SetIntent(intent string, rate float64) *Sampler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Intents == nil {
+		s.Intents = map[string]float64{}
+	}
+	s.Intents[intent] = rate
+	return s
+}
+
+// Keep returns whether an item with the given key and intent is kept
+// and the rate that has been applied
+func (s *Sampler) Keep(key, intent string, notHandled bool) (bool, float64) {
+	if notHandled && s.KeepNotHandled {
+		return true, 1
+	}
+	s.mu.RLock()
+	rate := s.Rate
+	if r, ok := s.Intents[intent]; ok {
+		rate = r
+	}
+	s.mu.RUnlock()
+	if rate >= 1 {
+		return true, 1
+	}
+	sum := sha256.Sum256([]byte(key))
+	position := float64(binary.BigEndian.Uint64(sum[:8])>>11) / (1 << 53)
+	return position < rate, rate
+}
+
+// apply returns a copy of the given payload containing only the items
+// that are kept, or nil if none are, and the indices of the items that
+// have been sampled out
+func (s *Sampler) apply(v interface{}) (interface{}, []int, error) {
+	switch p := v.(type) {
+	case *Message:
+		messages, dropped := s.messages(Messages{*p})
+		if len(messages) == 0 {
+			return nil, dropped, nil
+		}
+		return &messages[0], dropped, nil
+	case *Messages:
+		messages, dropped := s.messages(*p)
+		if len(messages) == 0 {
+			return nil, dropped, nil
+		}
+		return &messages, dropped, nil
+	case *Event:
+		events, dropped := s.events(Events{*p})
+		if len(events) == 0 {
+			return nil, dropped, nil
+		}
+		return &events[0], dropped, nil
+	case *Events:
+		events, dropped := s.events(*p)
+		if len(events) == 0 {
+			return nil, dropped, nil
+		}
+		return &events, dropped, nil
+	case *FacebookMessage:
+		messages, dropped, err := s.facebookMessages(FacebookMessages{*p})
+		if err != nil || len(messages) == 0 {
+			return nil, dropped, err
+		}
+		return &messages[0], dropped, nil
+	case *FacebookMessages:
+		messages, dropped, err := s.facebookMessages(*p)
+		if err != nil || len(messages) == 0 {
+			return nil, dropped, err
+		}
+		return &messages, dropped, nil
+	case *FacebookRequestResponse:
+		pairs, dropped, err := s.facebookRequestResponses(FacebookRequestResponses{*p})
+		if err != nil || len(pairs) == 0 {
+			return nil, dropped, err
+		}
+		return &pairs[0], dropped, nil
+	case *FacebookRequestResponses:
+		pairs, dropped, err := s.facebookRequestResponses(*p)
+		if err != nil || len(pairs) == 0 {
+			return nil, dropped, err
+		}
+		return &pairs, dropped, nil
+	}
+	return v, nil, nil
+}
+
+func (s *Sampler) messages(messages Messages) (Messages, []int) {
+	result := Messages{}
+	var dropped []int
+	for i, m := range messages {
+		key := m.UserID
+		if s.BySession && m.SessionID != "" {
+			key = m.SessionID
+		}
+		keep, rate := s.Keep(key, m.Intent, m.NotHandled)
+		if !keep {
+			dropped = append(dropped, i)
+			continue
+		}
+		m.Version = annotateVersion(m.Version, rate)
+		result = append(result, m)
+	}
+	return result, dropped
+}
+
+func (s *Sampler) events(events Events) (Events, []int) {
+	result := Events{}
+	var dropped []int
+	for i, e := range events {
+		keep, rate := s.Keep(e.UserID, e.Intent, false)
+		if !keep {
+			dropped = append(dropped, i)
+			continue
+		}
+		e.Properties = append(e.Properties[:len(e.Properties):len(e.Properties)],
+			EventProperty{Name: SampleRateProperty, FloatValue: rate})
+		result = append(result, e)
+	}
+	return result, dropped
+}
+
+func (s *Sampler) facebookMessages(messages FacebookMessages) (FacebookMessages, []int, error) {
+	result := FacebookMessages{}
+	var dropped []int
+	for i, f := range messages {
+		payload, err := genericJSON(f.Payload)
+		if err != nil {
+			return nil, nil, err
+		}
+		fields, keep := s.facebookFields(f.Fields, facebookID(payload, "sender"))
+		if !keep {
+			dropped = append(dropped, i)
+			continue
+		}
+		f.Fields = fields
+		result = append(result, f)
+	}
+	return result, dropped, nil
+}
+
+func (s *Sampler) facebookRequestResponses(pairs FacebookRequestResponses) (FacebookRequestResponses, []int, error) {
+	result := FacebookRequestResponses{}
+	var dropped []int
+	for i, f := range pairs {
+		request, err := genericJSON(f.Request)
+		if err != nil {
+			return nil, nil, err
+		}
+		fields, keep := s.facebookFields(f.Fields, facebookID(request, "recipient"))
+		if !keep {
+			dropped = append(dropped, i)
+			continue
+		}
+		f.Fields = fields
+		result = append(result, f)
+	}
+	return result, dropped, nil
+}
+
+// facebookFields returns an annotated copy of the given fields and
+// whether the item using them is kept
+func (s *Sampler) facebookFields(fields *FacebookFields, userID string) (*FacebookFields, bool) {
+	annotated := FacebookFields{}
+	if fields != nil {
+		annotated = *fields
+	}
+	keep, rate := s.Keep(userID, annotated.Intent, annotated.NotHandled)
+	annotated.Version = annotateVersion(annotated.Version, rate)
+	return &annotated, keep
+}
+
+// annotateVersion appends the sample rate to the given version
+func annotateVersion(version string, rate float64) string {
+	annotation := SampleRateProperty + "=" + strconv.FormatFloat(rate, 'g', -1, 64)
+	if version == "" {
+		return annotation
+	}
+	return version + ";" + annotation
+}
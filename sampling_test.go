@@ -0,0 +1,232 @@
+package chatbase
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestSampler_Keep(t *testing.T) {
+	s := NewSampler(0.1).SetIntent("purchase", 1).SetIntent("smalltalk", 0.05)
+
+	kept := 0
+	for i := 0; i < 10000; i++ {
+		key := "user-" + strconv.Itoa(i)
+		keep, rate := s.Keep(key, "greet", false)
+		if rate != 0.1 {
+			t.Fatalf("Expected rate 0.1, got %v", rate)
+		}
+		if again, _ := s.Keep(key, "other", false); again != keep {
+			t.Fatalf("Expected decision for %q to be deterministic", key)
+		}
+		if smalltalk, _ := s.Keep(key, "smalltalk", false); smalltalk && !keep {
+			t.Fatalf("Expected %q to be kept at the higher rate", key)
+		}
+		if keep {
+			kept++
+		}
+	}
+	if kept < 900 || kept > 1100 {
+		t.Errorf("Expected about 1000 kept conversations, got %d", kept)
+	}
+
+	if keep, rate := s.Keep("anyone", "purchase", false); !keep || rate != 1 {
+		t.Errorf("Expected purchase intent to be kept, got %v %v", keep, rate)
+	}
+	if keep, rate := (&Sampler{}).Keep("anyone", "", false); keep || rate != 0 {
+		t.Errorf("Expected zero rate to drop everything, got %v %v", keep, rate)
+	}
+	if keep, rate := NewSampler(0).Keep("anyone", "", true); !keep || rate != 1 {
+		t.Errorf("Expected not handled message to be kept, got %v %v", keep, rate)
+	}
+	if keep, _ := (&Sampler{}).Keep("anyone", "", true); keep {
+		t.Error("Expected not handled message to be dropped")
+	}
+}
+
+func TestSampler_SetIntent(t *testing.T) {
+	s := NewSampler(0)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			s.SetIntent("intent-"+strconv.Itoa(i), 1)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		s.Keep("anyone", "intent-"+strconv.Itoa(i), false)
+	}
+	<-done
+	if keep, rate := s.Keep("anyone", "intent-99", false); !keep || rate != 1 {
+		t.Errorf("Expected intent rate to be applied, got %v %v", keep, rate)
+	}
+}
+
+func TestSampler_apply(t *testing.T) {
+	s := NewSampler(0.5)
+	// user-0 is kept and user-1 is dropped at a rate of 0.5
+	if keep, _ := s.Keep("user-0", "", false); !keep {
+		t.Fatal("Expected user-0 to be kept")
+	}
+	if keep, _ := s.Keep("user-1", "", false); keep {
+		t.Fatal("Expected user-1 to be dropped")
+	}
+
+	tests := []struct {
+		name            string
+		sampler         *Sampler
+		payload         interface{}
+		expected        interface{}
+		expectedDropped []int
+	}{
+		{
+			"messages",
+			s,
+			&Messages{
+				{UserID: "user-0", Version: "1.0"},
+				{UserID: "user-1"},
+				{UserID: "user-1", NotHandled: true},
+			},
+			&Messages{
+				{UserID: "user-0", Version: "1.0;sample_rate=0.5"},
+				{UserID: "user-1", NotHandled: true, Version: "sample_rate=1"},
+			},
+			[]int{1},
+		},
+		{
+			"by session",
+			&Sampler{Rate: 0.5, BySession: true},
+			&Messages{
+				{UserID: "user-0", SessionID: "user-1"},
+				{UserID: "user-1", SessionID: "user-0"},
+			},
+			&Messages{
+				{UserID: "user-1", SessionID: "user-0", Version: "sample_rate=0.5"},
+			},
+			[]int{0},
+		},
+		{
+			"sampled out message",
+			s,
+			&Message{UserID: "user-1"},
+			nil,
+			[]int{0},
+		},
+		{
+			"event",
+			s,
+			&Event{UserID: "user-0", Intent: "signup", Properties: []EventProperty{{Name: "plan", StringValue: "pro"}}},
+			&Event{UserID: "user-0", Intent: "signup", Properties: []EventProperty{
+				{Name: "plan", StringValue: "pro"},
+				{Name: SampleRateProperty, FloatValue: 0.5},
+			}},
+			nil,
+		},
+		{
+			"facebook messages",
+			s,
+			&FacebookMessages{
+				{Payload: json.RawMessage(`{"sender":{"id":"user-0"}}`)},
+				{Payload: json.RawMessage(`{"sender":{"id":"user-1"}}`), Fields: &FacebookFields{Intent: "help"}},
+			},
+			&FacebookMessages{
+				{Payload: json.RawMessage(`{"sender":{"id":"user-0"}}`), Fields: &FacebookFields{Version: "sample_rate=0.5"}},
+			},
+			[]int{1},
+		},
+		{
+			"facebook request response",
+			s,
+			&FacebookRequestResponse{
+				Request: map[string]interface{}{"recipient": map[string]interface{}{"id": "user-1"}},
+				Fields:  &FacebookFields{NotHandled: true, Version: "2"},
+			},
+			&FacebookRequestResponse{
+				Request: map[string]interface{}{"recipient": map[string]interface{}{"id": "user-1"}},
+				Fields:  &FacebookFields{NotHandled: true, Version: "2;sample_rate=1"},
+			},
+			nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			before, _ := json.Marshal(test.payload)
+			result, dropped, err := test.sampler.apply(test.payload)
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(test.expectedDropped, dropped) {
+				t.Errorf("Expected dropped items %v, got %v", test.expectedDropped, dropped)
+			}
+			if !reflect.DeepEqual(test.expected, result) {
+				t.Errorf("Expected %#v, got %#v", test.expected, result)
+			}
+			if after, _ := json.Marshal(test.payload); string(before) != string(after) {
+				t.Errorf("Expected payload to be unchanged, got %s", after)
+			}
+		})
+	}
+}
+
+func TestWithSampler(t *testing.T) {
+	oldMessage, oldMessages := messageEndpoint, messagesEndpoint
+	defer func() { messageEndpoint, messagesEndpoint = oldMessage, oldMessages }()
+
+	var received []Message
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/batch" {
+			var body struct {
+				Messages []Message `json:"messages"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			received = append(received, body.Messages...)
+			w.Write([]byte(`{"all_succeeded":true,"status":200,"responses":[{"message_id":"2","status":200}]}`))
+			return
+		}
+		var m Message
+		json.NewDecoder(r.Body).Decode(&m)
+		received = append(received, m)
+		w.Write([]byte(`{"message_id":"1","status":200}`))
+	}))
+	defer ts.Close()
+	messageEndpoint = ts.URL
+	messagesEndpoint = ts.URL + "/batch"
+
+	c := New("key", WithConsentRegistry(NewConsentRegistry(FullConsent).OptOut("user-0")), WithSampler(NewSampler(0.5)))
+	if res, err := c.UserMessage("user-0", PlatformWeb).Submit(); err != nil || res.Withheld != consentPipeline {
+		t.Errorf("Expected consent to be checked first, got %#v %v", res, err)
+	}
+	if res, err := c.UserMessage("user-1", PlatformWeb).Submit(); err != nil || !res.Status.OK() || res.Withheld != samplingPipeline {
+		t.Errorf("Expected sampled out result, got %#v %v", res, err)
+	}
+	if _, err := c.UserMessage("user-1", PlatformWeb).SetNotHandled(true).Submit(); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if len(received) != 1 || received[0].Version != "sample_rate=1" {
+		t.Errorf("Unexpected submissions %v", received)
+	}
+
+	messages := Messages{}
+	messages.Append(
+		c.UserMessage("user-1", PlatformWeb),
+		c.UserMessage("user-0", PlatformWeb),
+		c.UserMessage("user-1", PlatformWeb).SetNotHandled(true),
+	)
+	res, err := messages.Submit()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	var withheld []string
+	for _, r := range res.Responses {
+		withheld = append(withheld, r.Withheld)
+	}
+	if expected := []string{samplingPipeline, consentPipeline, ""}; !reflect.DeepEqual(expected, withheld) {
+		t.Errorf("Expected aligned responses %v, got %#v", expected, res.Responses)
+	}
+	if res.Responses[2].MessageID != "2" {
+		t.Errorf("Expected submitted message to keep its response, got %#v", res.Responses[2])
+	}
+}
@@ -0,0 +1,104 @@
+package slack
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+// Handler wraps the handler for Slack's Events API and interactive
+// payloads. It verifies the request signature, answers URL verification
+// challenges and records user messages before passing the request on.
+// All requests are rejected when SigningSecret is empty
+type Handler struct {
+	Client        *chatbase.Client
+	SigningSecret string
+	Next          http.Handler
+	// ErrorHandler is called when a request cannot be recorded. Errors are
+	// discarded when it is nil
+	ErrorHandler func(error)
+}
+
+// NewHandler returns a new Handler using the given client, signing secret
+// and handler
+func NewHandler(client *chatbase.Client, signingSecret string, next http.Handler) *Handler {
+	return &Handler{
+		Client:        client,
+		SigningSecret: signingSecret,
+		Next:          next,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.SigningSecret == "" {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	body, bodyErr := ioutil.ReadAll(r.Body)
+	if bodyErr != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	if err := VerifySignature(r.Header, body, h.SigningSecret, time.Now()); err != nil {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		h.recordInteraction(body)
+		h.Next.ServeHTTP(w, r)
+		return
+	}
+
+	var callback EventCallback
+	if err := json.Unmarshal(body, &callback); err != nil {
+		h.handleError(err)
+		h.Next.ServeHTTP(w, r)
+		return
+	}
+	if callback.Type == "url_verification" {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(callback.Challenge))
+		return
+	}
+	if callback.Type == "event_callback" && callback.Event != nil {
+		if message, err := UserMessage(h.Client, callback.Event); err == nil {
+			chatbase.SubmitAsync(h.ErrorHandler, message)
+		} else if err != ErrUnsupportedEvent {
+			h.handleError(err)
+		}
+	}
+	h.Next.ServeHTTP(w, r)
+}
+
+func (h *Handler) recordInteraction(body []byte) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		h.handleError(err)
+		return
+	}
+	var payload InteractionPayload
+	if err := json.Unmarshal([]byte(values.Get("payload")), &payload); err != nil {
+		h.handleError(err)
+		return
+	}
+	message, messageErr := InteractionMessage(h.Client, &payload)
+	if messageErr != nil {
+		h.handleError(messageErr)
+		return
+	}
+	chatbase.SubmitAsync(h.ErrorHandler, message)
+}
+
+func (h *Handler) handleError(err error) {
+	if err != nil && h.ErrorHandler != nil {
+		h.ErrorHandler(err)
+	}
+}
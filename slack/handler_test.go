@@ -0,0 +1,108 @@
+package slack
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (r roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r(req)
+}
+
+func captureMessages(t *testing.T) chan chatbase.Message {
+	submitted := make(chan chatbase.Message, 1)
+	chatbase.SetAPITransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var m chatbase.Message
+		json.NewDecoder(r.Body).Decode(&m)
+		submitted <- m
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"status":200}`)),
+		}, nil
+	}))
+	return submitted
+}
+
+func TestHandler(t *testing.T) {
+	defer chatbase.SetAPITransport(nil)
+	submitted := captureMessages(t)
+
+	event := `{"type":"event_callback","event":{"type":"message","user":"U1","text":"hi","ts":"1.0"}}`
+	interaction := "payload=" + url.QueryEscape(`{"type":"block_actions","user":{"id":"U2"},"actions":[{"action_id":"a","value":"yes"}]}`)
+	tests := []struct {
+		name            string
+		body            string
+		contentType     string
+		secret          string
+		expectedCode    int
+		expectedBody    string
+		expectNext      bool
+		expectedMessage string
+	}{
+		{"event", event, "application/json", "secret", http.StatusOK, "", true, "U1:hi"},
+		{"interaction", interaction, "application/x-www-form-urlencoded", "secret", http.StatusOK, "", true, "U2:yes"},
+		{"challenge", `{"type":"url_verification","challenge":"abc123"}`, "application/json", "secret", http.StatusOK, "abc123", false, ""},
+		{"bot message", `{"type":"event_callback","event":{"type":"message","bot_id":"B1","text":"hi","ts":"1.0"}}`, "application/json", "secret", http.StatusOK, "", true, ""},
+		{"bad signature", event, "application/json", "nope", http.StatusUnauthorized, "Unauthorized\n", false, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calledNext := false
+			h := NewHandler(chatbase.New("key"), "secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := ioutil.ReadAll(r.Body)
+				if string(b) != test.body {
+					t.Errorf("Expected body to be passed on, got %v", string(b))
+				}
+				calledNext = true
+			}))
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(test.body))
+			req.Header = sign(test.secret, time.Now().Unix(), test.body)
+			req.Header.Set("Content-Type", test.contentType)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != test.expectedCode || rec.Body.String() != test.expectedBody {
+				t.Errorf("Unexpected response %v %v", rec.Code, rec.Body.String())
+			}
+			if calledNext != test.expectNext {
+				t.Errorf("Expected next to be called: %v", test.expectNext)
+			}
+			if test.expectedMessage == "" {
+				return
+			}
+			select {
+			case m := <-submitted:
+				if s := m.UserID + ":" + m.Message; s != test.expectedMessage {
+					t.Errorf("Expected %v, got %v", test.expectedMessage, s)
+				}
+			case <-time.After(time.Second):
+				t.Error("Expected message to be submitted")
+			}
+		})
+	}
+}
+
+func TestHandler_EmptySigningSecret(t *testing.T) {
+	called := false
+	h := NewHandler(chatbase.New("key"), "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	body := `{"type":"event_callback","event":{"type":"message","user":"U1","text":"hi","ts":"1.0"}}`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header = sign("", time.Now().Unix(), body)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized || called {
+		t.Errorf("Expected payload signed with empty secret to be rejected, got %v", rec.Code)
+	}
+}
@@ -0,0 +1,215 @@
+/*
+Package slack converts Slack Events API callbacks, interactive payloads and
+chat.postMessage calls into Chatbase messages.
+
+Messages are recorded using the Slack user id, thread timestamps are used as
+session ids so that each thread is a session in Chatbase:
+
+	client := chatbase.New("MY-API-KEY")
+	http.Handle("/slack/events", slack.NewHandler(client, "SIGNING-SECRET", eventHandler))
+
+	// record replies sent by the bot, addressed to the user in the context
+	slackClient := &http.Client{Transport: slack.NewTransport(client, nil)}
+	req = req.WithContext(slack.ContextWithUserID(req.Context(), "U123"))
+*/
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+// MaxSignatureAge is the maximum age of a request timestamp that
+// is accepted when verifying signatures
+const MaxSignatureAge = 5 * time.Minute
+
+// Errors returned when a request signature cannot be verified
+var (
+	ErrBadSignature = errors.New("request signature does not match")
+	ErrStaleRequest = errors.New("request timestamp is too old")
+)
+
+// ErrUnsupportedEvent is returned for events that are not user messages
+var ErrUnsupportedEvent = errors.New("event is not a user message")
+
+// EventCallback is the outer payload sent to an Events API endpoint
+type EventCallback struct {
+	Token     string `json:"token"`
+	TeamID    string `json:"team_id"`
+	Type      string `json:"type"`
+	Challenge string `json:"challenge,omitempty"`
+	EventID   string `json:"event_id,omitempty"`
+	EventTime int64  `json:"event_time,omitempty"`
+	Event     *Event `json:"event,omitempty"`
+}
+
+// Event is a message or app_mention event
+type Event struct {
+	Type     string `json:"type"`
+	Subtype  string `json:"subtype,omitempty"`
+	User     string `json:"user,omitempty"`
+	BotID    string `json:"bot_id,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Channel  string `json:"channel,omitempty"`
+	TS       string `json:"ts,omitempty"`
+	ThreadTS string `json:"thread_ts,omitempty"`
+}
+
+// InteractionPayload is the payload sent when a user interacts with
+// buttons, menus or shortcuts
+type InteractionPayload struct {
+	Type      string `json:"type"`
+	TriggerID string `json:"trigger_id,omitempty"`
+	User      struct {
+		ID string `json:"id"`
+	} `json:"user"`
+	Channel struct {
+		ID string `json:"id"`
+	} `json:"channel"`
+	Container struct {
+		MessageTS string `json:"message_ts,omitempty"`
+		ThreadTS  string `json:"thread_ts,omitempty"`
+	} `json:"container"`
+	Actions []Action `json:"actions,omitempty"`
+}
+
+// Action is a single action of an interaction payload
+type Action struct {
+	ActionID       string `json:"action_id"`
+	Value          string `json:"value,omitempty"`
+	ActionTS       string `json:"action_ts,omitempty"`
+	SelectedOption *struct {
+		Value string `json:"value"`
+	} `json:"selected_option,omitempty"`
+}
+
+// PostMessage contains the parameters of a chat.postMessage call
+type PostMessage struct {
+	Channel  string `json:"channel"`
+	Text     string `json:"text,omitempty"`
+	ThreadTS string `json:"thread_ts,omitempty"`
+}
+
+// PostMessageResponse is the response to a chat.postMessage call
+type PostMessageResponse struct {
+	OK      bool   `json:"ok"`
+	Channel string `json:"channel,omitempty"`
+	TS      string `json:"ts,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// VerifySignature checks the X-Slack-Signature header of a request
+// against the given body and signing secret
+func VerifySignature(header http.Header, body []byte, secret string, now time.Time) error {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrBadSignature
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); math.Abs(float64(age)) > float64(MaxSignatureAge) {
+		return ErrStaleRequest
+	}
+	signature := header.Get("X-Slack-Signature")
+	expected, decodeErr := hex.DecodeString(strings.TrimPrefix(signature, "v0="))
+	if !strings.HasPrefix(signature, "v0=") || decodeErr != nil {
+		return ErrBadSignature
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// UserMessage converts a message or app_mention event into a user message.
+// Messages posted by bots and other kinds of events return ErrUnsupportedEvent
+func UserMessage(client *chatbase.Client, e *Event) (*chatbase.Message, error) {
+	if e.Type != "message" && e.Type != "app_mention" {
+		return nil, ErrUnsupportedEvent
+	}
+	if e.BotID != "" || e.Subtype != "" || e.User == "" {
+		return nil, ErrUnsupportedEvent
+	}
+	m := client.UserMessage(e.User, chatbase.PlatformSlack).
+		SetMessage(e.Text).
+		SetSessionID(threadID(e.ThreadTS, e.TS))
+	if ts, ok := timeStamp(e.TS); ok {
+		m.SetTimeStamp(ts)
+	}
+	return m, nil
+}
+
+// InteractionMessage converts an interaction payload into a user message
+// using the value of its first action as the message
+func InteractionMessage(client *chatbase.Client, p *InteractionPayload) (*chatbase.Message, error) {
+	if p.User.ID == "" {
+		return nil, ErrUnsupportedEvent
+	}
+	m := client.UserMessage(p.User.ID, chatbase.PlatformSlack).
+		SetSessionID(threadID(p.Container.ThreadTS, p.Container.MessageTS))
+	if len(p.Actions) > 0 {
+		action := p.Actions[0]
+		value := action.Value
+		if action.SelectedOption != nil {
+			value = action.SelectedOption.Value
+		}
+		m.SetMessage(value)
+		if ts, ok := timeStamp(action.ActionTS); ok {
+			m.SetTimeStamp(ts)
+		}
+	}
+	return m, nil
+}
+
+// AgentMessage converts a chat.postMessage call into an agent message
+// addressed to the user with the given id. The thread is used as session
+func AgentMessage(client *chatbase.Client, userID string, p *PostMessage, res *PostMessageResponse) *chatbase.Message {
+	m := client.AgentMessage(userID, chatbase.PlatformSlack).SetMessage(p.Text)
+	session := p.ThreadTS
+	if res != nil {
+		if session == "" {
+			session = res.TS
+		}
+		if ts, ok := timeStamp(res.TS); ok {
+			m.SetTimeStamp(ts)
+		}
+	}
+	return m.SetSessionID(session)
+}
+
+func threadID(threadTS, ts string) string {
+	if threadTS != "" {
+		return threadTS
+	}
+	return ts
+}
+
+// timeStamp converts Slack's "seconds.micros" timestamps into UNIX milliseconds
+func timeStamp(ts string) (int64, bool) {
+	parts := strings.SplitN(ts, ".", 2)
+	seconds, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	millis := seconds * 1000
+	if len(parts) == 2 {
+		fraction := (parts[1] + "000")[:3]
+		ms, err := strconv.ParseInt(fraction, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		millis += ms
+	}
+	return millis, true
+}
@@ -0,0 +1,146 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+func sign(secret string, ts int64, body string) http.Header {
+	timestamp := strconv.FormatInt(ts, 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":" + body))
+	return http.Header{
+		"X-Slack-Request-Timestamp": {timestamp},
+		"X-Slack-Signature":         {"v0=" + hex.EncodeToString(mac.Sum(nil))},
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	now := time.Unix(1531420618, 0)
+	tests := []struct {
+		name     string
+		header   http.Header
+		body     string
+		expected error
+	}{
+		{"default", sign("secret", 1531420618, "hello"), "hello", nil},
+		{"bad secret", sign("nope", 1531420618, "hello"), "hello", ErrBadSignature},
+		{"bad body", sign("secret", 1531420618, "hello"), "zalgo", ErrBadSignature},
+		{"stale", sign("secret", 1531420000, "hello"), "hello", ErrStaleRequest},
+		{"missing headers", http.Header{}, "hello", ErrBadSignature},
+		{"bad signature format", http.Header{"X-Slack-Request-Timestamp": {"1531420618"}, "X-Slack-Signature": {"v1=abc"}}, "hello", ErrBadSignature},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := VerifySignature(test.header, []byte(test.body), "secret", now); err != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestUserMessage(t *testing.T) {
+	client := chatbase.New("key")
+	tests := []struct {
+		name        string
+		input       string
+		expectError bool
+		expected    *chatbase.Message
+	}{
+		{
+			"message",
+			`{"type":"message","user":"U1","text":"hi","channel":"C1","ts":"1355517523.000005"}`,
+			false,
+			&chatbase.Message{APIKey: "key", Type: chatbase.UserType, UserID: "U1", TimeStamp: 1355517523000, Platform: "Slack", Message: "hi", SessionID: "1355517523.000005"},
+		},
+		{
+			"app mention in thread",
+			`{"type":"app_mention","user":"U1","text":"<@B1> help","channel":"C1","ts":"1355517524.123456","thread_ts":"1355517523.000005"}`,
+			false,
+			&chatbase.Message{APIKey: "key", Type: chatbase.UserType, UserID: "U1", TimeStamp: 1355517524123, Platform: "Slack", Message: "<@B1> help", SessionID: "1355517523.000005"},
+		},
+		{"bot message", `{"type":"message","bot_id":"B1","text":"hi","ts":"1355517523.000005"}`, true, nil},
+		{"subtype", `{"type":"message","subtype":"message_changed","user":"U1","ts":"1355517523.000005"}`, true, nil},
+		{"other event", `{"type":"reaction_added","user":"U1"}`, true, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var e Event
+			if err := json.Unmarshal([]byte(test.input), &e); err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			result, err := UserMessage(client, &e)
+			if test.expectError != (err != nil) {
+				t.Errorf("Unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(test.expected, result) {
+				t.Errorf("Expected %#v, got %#v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestInteractionMessage(t *testing.T) {
+	client := chatbase.New("key")
+	tests := []struct {
+		name        string
+		input       string
+		expectError bool
+		expected    *chatbase.Message
+	}{
+		{
+			"button",
+			`{"type":"block_actions","user":{"id":"U1"},"container":{"message_ts":"1355517523.000005"},"actions":[{"action_id":"a","value":"yes","action_ts":"1355517530.5"}]}`,
+			false,
+			&chatbase.Message{APIKey: "key", Type: chatbase.UserType, UserID: "U1", TimeStamp: 1355517530500, Platform: "Slack", Message: "yes", SessionID: "1355517523.000005"},
+		},
+		{
+			"select",
+			`{"type":"block_actions","user":{"id":"U1"},"container":{"message_ts":"2.0","thread_ts":"1.0"},"actions":[{"action_id":"a","selected_option":{"value":"blue"},"action_ts":"3"}]}`,
+			false,
+			&chatbase.Message{APIKey: "key", Type: chatbase.UserType, UserID: "U1", TimeStamp: 3000, Platform: "Slack", Message: "blue", SessionID: "1.0"},
+		},
+		{"no user", `{"type":"block_actions"}`, true, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var p InteractionPayload
+			if err := json.Unmarshal([]byte(test.input), &p); err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			result, err := InteractionMessage(client, &p)
+			if test.expectError != (err != nil) {
+				t.Errorf("Unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(test.expected, result) {
+				t.Errorf("Expected %#v, got %#v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestAgentMessage(t *testing.T) {
+	client := chatbase.New("key")
+	t.Run("reply in thread", func(t *testing.T) {
+		result := AgentMessage(client, "U1", &PostMessage{Channel: "C1", Text: "hello", ThreadTS: "1.0"}, &PostMessageResponse{OK: true, TS: "2.5"})
+		expected := &chatbase.Message{APIKey: "key", Type: chatbase.AgentType, UserID: "U1", TimeStamp: 2500, Platform: "Slack", Message: "hello", SessionID: "1.0"}
+		if !reflect.DeepEqual(expected, result) {
+			t.Errorf("Expected %#v, got %#v", expected, result)
+		}
+	})
+	t.Run("new thread", func(t *testing.T) {
+		result := AgentMessage(client, "U1", &PostMessage{Channel: "C1", Text: "hello"}, &PostMessageResponse{OK: true, TS: "2.5"})
+		if result.SessionID != "2.5" {
+			t.Errorf("Unexpected session id %v", result.SessionID)
+		}
+	})
+}
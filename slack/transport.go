@@ -0,0 +1,136 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+// ErrMissingUser is reported when a chat.postMessage call is made without
+// a user id in the request's context
+var ErrMissingUser = errors.New("context does not contain the addressed user")
+
+type userIDKey struct{}
+
+// ContextWithUserID returns a copy of ctx carrying the id of the user a
+// reply is addressed to. Transport requires it for recording replies as
+// chat.postMessage calls only contain the channel
+func ContextWithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// UserIDFromContext returns the user id stored in ctx, if any
+func UserIDFromContext(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(userIDKey{}).(string)
+	return userID, ok && userID != ""
+}
+
+// Transport is an http.RoundTripper for the client used for calling the
+// Slack Web API. Successful chat.postMessage calls are recorded as agent
+// messages addressed to the user stored in the request's context using
+// ContextWithUserID, any other request is passed through unchanged
+type Transport struct {
+	Client *chatbase.Client
+	// Base is the RoundTripper used for performing the actual requests,
+	// http.DefaultTransport is used when it is nil
+	Base http.RoundTripper
+	// ErrorHandler is called when a call cannot be recorded. Errors are
+	// discarded when it is nil
+	ErrorHandler func(error)
+}
+
+// NewTransport returns a new Transport using the given client and base transport
+func NewTransport(client *chatbase.Client, base http.RoundTripper) *Transport {
+	return &Transport{
+		Client: client,
+		Base:   base,
+	}
+}
+
+// RoundTrip performs the request and records chat.postMessage calls
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, "/chat.postMessage") || req.Body == nil {
+		return t.base().RoundTrip(req)
+	}
+
+	requestBody, requestErr := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if requestErr != nil {
+		return nil, requestErr
+	}
+	req = req.Clone(req.Context())
+	req.Body = ioutil.NopCloser(bytes.NewReader(requestBody))
+
+	res, err := t.base().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	responseBody, responseErr := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if responseErr != nil {
+		return nil, responseErr
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(responseBody))
+
+	post, postErr := parsePostMessage(req.Header.Get("Content-Type"), requestBody)
+	if postErr != nil {
+		t.handleError(postErr)
+		return res, nil
+	}
+	var result PostMessageResponse
+	if err := json.Unmarshal(responseBody, &result); err != nil {
+		t.handleError(err)
+		return res, nil
+	}
+	if !result.OK {
+		return res, nil
+	}
+	userID, ok := UserIDFromContext(req.Context())
+	if !ok {
+		t.handleError(ErrMissingUser)
+		return res, nil
+	}
+	chatbase.SubmitAsync(t.ErrorHandler, AgentMessage(t.Client, userID, post, &result))
+	return res, nil
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+func (t *Transport) handleError(err error) {
+	if err != nil && t.ErrorHandler != nil {
+		t.ErrorHandler(err)
+	}
+}
+
+// parsePostMessage reads the call's parameters, which can be
+// sent either as JSON or form encoded
+func parsePostMessage(contentType string, body []byte) (*PostMessage, error) {
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, err
+		}
+		return &PostMessage{
+			Channel:  values.Get("channel"),
+			Text:     values.Get("text"),
+			ThreadTS: values.Get("thread_ts"),
+		}, nil
+	}
+	var post PostMessage
+	if err := json.Unmarshal(body, &post); err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
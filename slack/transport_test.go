@@ -0,0 +1,119 @@
+package slack
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+func TestTransport(t *testing.T) {
+	defer chatbase.SetAPITransport(nil)
+	submitted := captureMessages(t)
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		if strings.Contains(string(b), "fail") {
+			w.Write([]byte(`{"ok":false,"error":"channel_not_found"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"channel":"C1","ts":"1503435956.000247"}`))
+	}))
+	defer api.Close()
+
+	var reported []error
+	transport := NewTransport(chatbase.New("key"), nil)
+	transport.ErrorHandler = func(err error) { reported = append(reported, err) }
+	httpClient := &http.Client{Transport: transport}
+	tests := []struct {
+		name            string
+		path            string
+		contentType     string
+		body            string
+		userID          string
+		expectedMessage string
+	}{
+		{"json", "/api/chat.postMessage", "application/json", `{"channel":"C1","text":"hello","thread_ts":"1.0"}`, "U1", "U1:hello:1.0"},
+		{"form", "/api/chat.postMessage", "application/x-www-form-urlencoded", url.Values{"channel": {"C1"}, "text": {"hi there"}}.Encode(), "U1", "U1:hi there:1503435956.000247"},
+		{"failed call", "/api/chat.postMessage", "application/json", `{"channel":"C1","text":"fail"}`, "U1", ""},
+		{"no user", "/api/chat.postMessage", "application/json", `{"channel":"C1","text":"hello"}`, "", ""},
+		{"other method", "/api/users.info", "application/json", `{"user":"U1"}`, "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, api.URL+test.path, strings.NewReader(test.body))
+			req.Header.Set("Content-Type", test.contentType)
+			if test.userID != "" {
+				req = req.WithContext(ContextWithUserID(req.Context(), test.userID))
+			}
+			res, err := httpClient.Do(req)
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			b, _ := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+			if !strings.Contains(string(b), `"ok"`) {
+				t.Errorf("Unexpected response body %s", b)
+			}
+			if test.expectedMessage == "" {
+				select {
+				case m := <-submitted:
+					t.Errorf("Unexpected message %#v", m)
+				case <-time.After(50 * time.Millisecond):
+				}
+				return
+			}
+			select {
+			case m := <-submitted:
+				if s := m.UserID + ":" + m.Message + ":" + m.SessionID; s != test.expectedMessage || m.Type != chatbase.AgentType {
+					t.Errorf("Expected %v, got %v", test.expectedMessage, s)
+				}
+			case <-time.After(time.Second):
+				t.Error("Expected message to be submitted")
+			}
+		})
+	}
+	if len(reported) != 1 || reported[0] != ErrMissingUser {
+		t.Errorf("Expected missing user to be reported, got %v", reported)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestTransport_ResponseError(t *testing.T) {
+	transport := NewTransport(chatbase.New("key"), roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(failingReader{})}, nil
+	}))
+	req := httptest.NewRequest(http.MethodPost, "https://example.net/chat.postMessage", strings.NewReader(`{}`))
+	res, err := transport.RoundTrip(req)
+	if res != nil || err == nil {
+		t.Errorf("Expected error without response, got %v %v", res, err)
+	}
+}
+
+func TestTransport_Request(t *testing.T) {
+	transport := NewTransport(chatbase.New("key"), roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if b, _ := ioutil.ReadAll(req.Body); string(b) != `{"channel":"C1","text":"fail"}` {
+			t.Errorf("Expected body to be passed on, got %s", b)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(`{"ok":false}`))}, nil
+	}))
+	req := httptest.NewRequest(http.MethodPost, "https://slack.com/api/chat.postMessage", strings.NewReader(`{"channel":"C1","text":"fail"}`))
+	body := req.Body
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if req.Body != body {
+		t.Error("Expected request of the caller not to be modified")
+	}
+}
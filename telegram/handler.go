@@ -0,0 +1,65 @@
+package telegram
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+// Handler wraps the handler of a Telegram webhook and records each
+// incoming update as a user message before passing the request on
+type Handler struct {
+	Client *chatbase.Client
+	Next   http.Handler
+	// SecretToken is compared against the X-Telegram-Bot-Api-Secret-Token
+	// header when it is not empty
+	SecretToken string
+	// ErrorHandler is called when an update cannot be recorded. Errors are
+	// discarded when it is nil
+	ErrorHandler func(error)
+}
+
+// NewHandler returns a new Handler using the given client and handler
+func NewHandler(client *chatbase.Client, next http.Handler) *Handler {
+	return &Handler{
+		Client: client,
+		Next:   next,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.SecretToken != "" {
+		token := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(h.SecretToken)) != 1 {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+	}
+
+	body, bodyErr := ioutil.ReadAll(r.Body)
+	if bodyErr != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	var update Update
+	if err := json.Unmarshal(body, &update); err != nil {
+		h.handleError(err)
+	} else if message, err := UserMessage(h.Client, &update); err != nil {
+		h.handleError(err)
+	} else {
+		chatbase.SubmitAsync(h.ErrorHandler, message)
+	}
+	h.Next.ServeHTTP(w, r)
+}
+
+func (h *Handler) handleError(err error) {
+	if err != nil && h.ErrorHandler != nil {
+		h.ErrorHandler(err)
+	}
+}
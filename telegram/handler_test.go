@@ -0,0 +1,86 @@
+package telegram
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (r roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r(req)
+}
+
+func TestHandler(t *testing.T) {
+	defer chatbase.SetAPITransport(nil)
+	submitted := make(chan chatbase.Message, 1)
+	chatbase.SetAPITransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var m chatbase.Message
+		json.NewDecoder(r.Body).Decode(&m)
+		submitted <- m
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"status":200}`)),
+		}, nil
+	}))
+
+	update := `{"update_id":1,"message":{"message_id":2,"from":{"id":42},"chat":{"id":42},"date":1500000000,"text":"hi"}}`
+	tests := []struct {
+		name         string
+		body         string
+		token        string
+		expectedCode int
+		expectSubmit bool
+		expectError  bool
+	}{
+		{"default", update, "secret", http.StatusOK, true, false},
+		{"bad token", update, "nope", http.StatusUnauthorized, false, false},
+		{"unsupported update", `{"update_id":1}`, "secret", http.StatusOK, false, true},
+		{"bad payload", `{{`, "secret", http.StatusOK, false, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var nextBody string
+			var handlerErr error
+			h := NewHandler(chatbase.New("key"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := ioutil.ReadAll(r.Body)
+				nextBody = string(b)
+			}))
+			h.SecretToken = "secret"
+			h.ErrorHandler = func(err error) { handlerErr = err }
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(test.body))
+			req.Header.Set("X-Telegram-Bot-Api-Secret-Token", test.token)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != test.expectedCode {
+				t.Errorf("Expected status %v, got %v", test.expectedCode, rec.Code)
+			}
+			if test.expectedCode == http.StatusOK && nextBody != test.body {
+				t.Errorf("Expected body to be passed on, got %v", nextBody)
+			}
+			if test.expectError != (handlerErr != nil) {
+				t.Errorf("Unexpected error %v", handlerErr)
+			}
+			if !test.expectSubmit {
+				return
+			}
+			select {
+			case m := <-submitted:
+				if m.UserID != "42" || m.Message != "hi" || m.Platform != chatbase.PlatformTelegram {
+					t.Errorf("Unexpected message %#v", m)
+				}
+			case <-time.After(time.Second):
+				t.Error("Expected message to be submitted")
+			}
+		})
+	}
+}
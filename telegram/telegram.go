@@ -0,0 +1,167 @@
+/*
+Package telegram converts Telegram Bot API updates and sendMessage calls
+into Chatbase messages.
+
+Incoming updates are recorded as user messages, outgoing sendMessage calls
+as agent messages. User ids are taken from the Telegram user, timestamps from
+Telegram's "date" field and session ids from the id of the chat:
+
+	client := chatbase.New("MY-API-KEY")
+	http.Handle("/telegram", telegram.NewHandler(client, botHandler))
+*/
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+// ErrUnsupportedUpdate is returned when an update does not contain
+// any of the supported kinds of data
+var ErrUnsupportedUpdate = errors.New("update does not contain a message, edited message, callback query or inline query")
+
+// ChatID is the id of a chat, which can be either an integer or the
+// username of a channel (like "@channelname")
+type ChatID string
+
+// UnmarshalJSON normalizes int and string values into a string representation
+func (c *ChatID) UnmarshalJSON(b []byte) error {
+	var i int64
+	if err := json.Unmarshal(b, &i); err == nil {
+		*c = ChatID(strconv.FormatInt(i, 10))
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(b, &str); err == nil {
+		*c = ChatID(str)
+		return nil
+	}
+	return fmt.Errorf("could not unmarshal %s into ChatID", b)
+}
+
+// MarshalJSON serializes numeric ids as integers and usernames as strings
+func (c ChatID) MarshalJSON() ([]byte, error) {
+	if i, err := strconv.ParseInt(string(c), 10, 64); err == nil {
+		return json.Marshal(i)
+	}
+	return json.Marshal(string(c))
+}
+
+// Update is an incoming update as sent to a webhook
+type Update struct {
+	UpdateID      int64          `json:"update_id"`
+	Message       *Message       `json:"message,omitempty"`
+	EditedMessage *Message       `json:"edited_message,omitempty"`
+	CallbackQuery *CallbackQuery `json:"callback_query,omitempty"`
+	InlineQuery   *InlineQuery   `json:"inline_query,omitempty"`
+}
+
+// User is a Telegram user or bot
+type User struct {
+	ID        int64  `json:"id"`
+	IsBot     bool   `json:"is_bot,omitempty"`
+	FirstName string `json:"first_name,omitempty"`
+	Username  string `json:"username,omitempty"`
+}
+
+// Chat is a private chat, group or channel
+type Chat struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type,omitempty"`
+}
+
+// Message is a message sent in a chat
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	From      *User  `json:"from,omitempty"`
+	Chat      Chat   `json:"chat"`
+	Date      int64  `json:"date"`
+	EditDate  int64  `json:"edit_date,omitempty"`
+	Text      string `json:"text,omitempty"`
+	Caption   string `json:"caption,omitempty"`
+}
+
+// CallbackQuery is sent when a user presses a button of an inline keyboard
+type CallbackQuery struct {
+	ID      string   `json:"id"`
+	From    User     `json:"from"`
+	Message *Message `json:"message,omitempty"`
+	Data    string   `json:"data,omitempty"`
+}
+
+// InlineQuery is sent when a user queries the bot in inline mode
+type InlineQuery struct {
+	ID    string `json:"id"`
+	From  User   `json:"from"`
+	Query string `json:"query"`
+}
+
+// SendMessage contains the parameters of a sendMessage call
+type SendMessage struct {
+	ChatID           ChatID `json:"chat_id"`
+	Text             string `json:"text"`
+	ParseMode        string `json:"parse_mode,omitempty"`
+	ReplyToMessageID int64  `json:"reply_to_message_id,omitempty"`
+}
+
+// UserMessage converts the given update into a user message. Updates that
+// do not contain any of the supported kinds of data return ErrUnsupportedUpdate
+func UserMessage(client *chatbase.Client, u *Update) (*chatbase.Message, error) {
+	switch {
+	case u.Message != nil:
+		return fromMessage(client, u.Message, u.Message.Date), nil
+	case u.EditedMessage != nil:
+		date := u.EditedMessage.EditDate
+		if date == 0 {
+			date = u.EditedMessage.Date
+		}
+		return fromMessage(client, u.EditedMessage, date), nil
+	case u.CallbackQuery != nil:
+		q := u.CallbackQuery
+		m := client.UserMessage(formatID(q.From.ID), chatbase.PlatformTelegram).SetMessage(q.Data)
+		if q.Message != nil {
+			m.SetSessionID(formatID(q.Message.Chat.ID))
+		}
+		return m, nil
+	case u.InlineQuery != nil:
+		q := u.InlineQuery
+		return client.UserMessage(formatID(q.From.ID), chatbase.PlatformTelegram).SetMessage(q.Query), nil
+	}
+	return nil, ErrUnsupportedUpdate
+}
+
+// AgentMessage converts a sendMessage call into an agent message addressed
+// to the user with the given id, using the chat as session. The result
+// returned by Telegram is optional, if given its date is used as the message's
+// timestamp
+func AgentMessage(client *chatbase.Client, userID string, s *SendMessage, result *Message) *chatbase.Message {
+	m := client.AgentMessage(userID, chatbase.PlatformTelegram)
+	m.SetMessage(s.Text).SetSessionID(string(s.ChatID))
+	if result != nil && result.Date != 0 {
+		m.SetTimeStamp(result.Date * 1000)
+	}
+	return m
+}
+
+func fromMessage(client *chatbase.Client, msg *Message, date int64) *chatbase.Message {
+	userID := msg.Chat.ID
+	if msg.From != nil {
+		userID = msg.From.ID
+	}
+	text := msg.Text
+	if text == "" {
+		text = msg.Caption
+	}
+	return client.UserMessage(formatID(userID), chatbase.PlatformTelegram).
+		SetMessage(text).
+		SetTimeStamp(date * 1000).
+		SetSessionID(formatID(msg.Chat.ID))
+}
+
+func formatID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
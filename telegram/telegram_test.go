@@ -0,0 +1,137 @@
+package telegram
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+func TestChatID(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected ChatID
+	}{
+		{"int", `-100123`, "-100123"},
+		{"string", `"@channel"`, "@channel"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var id ChatID
+			if err := json.Unmarshal([]byte(test.input), &id); err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if id != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, id)
+			}
+			b, err := json.Marshal(id)
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if string(b) != test.input {
+				t.Errorf("Expected %v, got %s", test.input, b)
+			}
+		})
+	}
+	t.Run("bad value", func(t *testing.T) {
+		var id ChatID
+		if err := json.Unmarshal([]byte(`[1]`), &id); err == nil {
+			t.Error("Expected error, got nil")
+		}
+	})
+}
+
+func TestUserMessage(t *testing.T) {
+	oldTimeStamp := chatbase.TimeStamp
+	defer func() { chatbase.TimeStamp = oldTimeStamp }()
+	chatbase.TimeStamp = func() int64 { return 998877 }
+	client := chatbase.New("key")
+
+	tests := []struct {
+		name        string
+		input       string
+		expectError bool
+		expected    *chatbase.Message
+	}{
+		{
+			"message",
+			`{"update_id":1,"message":{"message_id":2,"from":{"id":42},"chat":{"id":-7},"date":1500000000,"text":"/start"}}`,
+			false,
+			&chatbase.Message{APIKey: "key", Type: chatbase.UserType, UserID: "42", TimeStamp: 1500000000000, Platform: "Telegram", Message: "/start", SessionID: "-7"},
+		},
+		{
+			"caption",
+			`{"update_id":1,"message":{"message_id":2,"from":{"id":42},"chat":{"id":42},"date":1500000000,"caption":"a photo"}}`,
+			false,
+			&chatbase.Message{APIKey: "key", Type: chatbase.UserType, UserID: "42", TimeStamp: 1500000000000, Platform: "Telegram", Message: "a photo", SessionID: "42"},
+		},
+		{
+			"edited message",
+			`{"update_id":1,"edited_message":{"message_id":2,"from":{"id":42},"chat":{"id":42},"date":1500000000,"edit_date":1500000100,"text":"fixed"}}`,
+			false,
+			&chatbase.Message{APIKey: "key", Type: chatbase.UserType, UserID: "42", TimeStamp: 1500000100000, Platform: "Telegram", Message: "fixed", SessionID: "42"},
+		},
+		{
+			"channel post without sender",
+			`{"update_id":1,"message":{"message_id":2,"chat":{"id":-100},"date":1500000000,"text":"news"}}`,
+			false,
+			&chatbase.Message{APIKey: "key", Type: chatbase.UserType, UserID: "-100", TimeStamp: 1500000000000, Platform: "Telegram", Message: "news", SessionID: "-100"},
+		},
+		{
+			"callback query",
+			`{"update_id":1,"callback_query":{"id":"q","from":{"id":42},"message":{"message_id":2,"chat":{"id":-7},"date":1},"data":"yes"}}`,
+			false,
+			&chatbase.Message{APIKey: "key", Type: chatbase.UserType, UserID: "42", TimeStamp: 998877, Platform: "Telegram", Message: "yes", SessionID: "-7"},
+		},
+		{
+			"inline query",
+			`{"update_id":1,"inline_query":{"id":"q","from":{"id":42},"query":"cats"}}`,
+			false,
+			&chatbase.Message{APIKey: "key", Type: chatbase.UserType, UserID: "42", TimeStamp: 998877, Platform: "Telegram", Message: "cats"},
+		},
+		{
+			"unsupported",
+			`{"update_id":1,"poll":{}}`,
+			true,
+			nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var update Update
+			if err := json.Unmarshal([]byte(test.input), &update); err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			result, err := UserMessage(client, &update)
+			if test.expectError != (err != nil) {
+				t.Errorf("Unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(test.expected, result) {
+				t.Errorf("Expected %#v, got %#v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestAgentMessage(t *testing.T) {
+	oldTimeStamp := chatbase.TimeStamp
+	defer func() { chatbase.TimeStamp = oldTimeStamp }()
+	chatbase.TimeStamp = func() int64 { return 998877 }
+	client := chatbase.New("key")
+
+	t.Run("default", func(t *testing.T) {
+		result := AgentMessage(client, "7", &SendMessage{ChatID: "42", Text: "hello"}, &Message{Date: 1500000000})
+		expected := &chatbase.Message{APIKey: "key", Type: chatbase.AgentType, UserID: "7", TimeStamp: 1500000000000, Platform: "Telegram", Message: "hello", SessionID: "42"}
+		if !reflect.DeepEqual(expected, result) {
+			t.Errorf("Expected %#v, got %#v", expected, result)
+		}
+	})
+	t.Run("without result", func(t *testing.T) {
+		result := AgentMessage(client, "7", &SendMessage{ChatID: "42", Text: "hello"}, nil)
+		if result.TimeStamp != 998877 {
+			t.Errorf("Unexpected timestamp %v", result.TimeStamp)
+		}
+	})
+}
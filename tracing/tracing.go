@@ -0,0 +1,102 @@
+/*
+Package tracing wraps calls to the Chatbase API in OpenTelemetry spans.
+
+Each call becomes a client span carrying the kind of endpoint, the number of
+submitted items, the HTTP status code and the status reported by Chatbase.
+When submitting using SubmitWithContext, the span becomes a child of the span
+stored in the given context:
+
+	tracer := tracing.New(nil)
+	client := chatbase.New("MY-API-KEY", chatbase.WithInterceptors(tracer.Intercept))
+
+	ctx, span := otel.Tracer("bot").Start(r.Context(), "handle message")
+	defer span.End()
+	res, err := client.UserMessage("user-123", chatbase.PlatformWeb).SubmitWithContext(ctx)
+*/
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans created by this package
+const instrumentationName = "github.com/m90/go-chatbase/v2/tracing"
+
+// Attribute keys used on spans created for API calls
+const (
+	attrEndpoint   = attribute.Key("chatbase.endpoint")
+	attrItemCount  = attribute.Key("chatbase.item_count")
+	attrStatus     = attribute.Key("chatbase.status")
+	attrMethod     = attribute.Key("http.request.method")
+	attrHTTPStatus = attribute.Key("http.response.status_code")
+)
+
+// Tracer creates spans for calls to the Chatbase API
+type Tracer struct {
+	provider trace.TracerProvider
+}
+
+// New returns a new Tracer using the given provider. The globally registered
+// provider is used at the time of each call when provider is nil
+func New(provider trace.TracerProvider) *Tracer {
+	return &Tracer{
+		provider: provider,
+	}
+}
+
+// Intercept is a chatbase.Interceptor wrapping the call in a client span
+func (t *Tracer) Intercept(ctx context.Context, req *chatbase.APIRequest, next chatbase.APICall) (*chatbase.APIResponse, error) {
+	ctx, span := t.tracer().Start(ctx, "chatbase "+req.Kind,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attrEndpoint.String(req.Kind),
+			attrItemCount.Int(req.Items),
+			attrMethod.String(req.Method),
+		),
+	)
+	defer span.End()
+
+	res, err := next(ctx, req)
+	// server errors are returned as errors by the client, so the
+	// span is marked as failed even though next did not fail
+	spanErr := err
+	switch {
+	case err != nil:
+	case res == nil:
+		spanErr = errors.New("no response")
+	case res.StatusCode >= http.StatusInternalServerError:
+		spanErr = fmt.Errorf("request failed with status %v", res.StatusCode)
+	}
+	if res != nil {
+		span.SetAttributes(attrHTTPStatus.Int(res.StatusCode))
+		var body struct {
+			Status *chatbase.Status `json:"status"`
+		}
+		if json.Unmarshal(res.Body, &body) == nil && body.Status != nil {
+			span.SetAttributes(attrStatus.Bool(body.Status.OK()))
+		}
+	}
+	if spanErr != nil {
+		span.RecordError(spanErr)
+		span.SetStatus(codes.Error, spanErr.Error())
+	}
+	return res, err
+}
+
+func (t *Tracer) tracer() trace.Tracer {
+	provider := t.provider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return provider.Tracer(instrumentationName)
+}
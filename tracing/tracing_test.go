@@ -0,0 +1,115 @@
+package tracing
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestTracer_Intercept(t *testing.T) {
+	defer chatbase.SetAPITransport(nil)
+	chatbase.SetAPITransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/events/insert") {
+			return &http.Response{StatusCode: http.StatusInternalServerError, Body: ioutil.NopCloser(strings.NewReader("internal server error"))}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(`{"all_succeeded":false,"status":400}`))}, nil
+	}))
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	client := chatbase.New("key", chatbase.WithInterceptors(New(provider).Intercept))
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+	messages := chatbase.Messages{}
+	messages.Append(client.UserMessage("user", chatbase.PlatformWeb), client.AgentMessage("user", chatbase.PlatformWeb))
+	if _, err := messages.SubmitWithContext(ctx); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if err := client.Event("user", "intent").SubmitWithContext(ctx); err == nil {
+		t.Error("Expected error, got nil")
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+	batch, event := spans[0], spans[1]
+
+	if batch.Name() != "chatbase messages" || batch.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("Unexpected span %v with parent %v", batch.Name(), batch.Parent())
+	}
+	attrs := spanAttributes(batch)
+	expected := map[attribute.Key]attribute.Value{
+		attrEndpoint:   attribute.StringValue("messages"),
+		attrItemCount:  attribute.IntValue(2),
+		attrMethod:     attribute.StringValue(http.MethodPost),
+		attrHTTPStatus: attribute.IntValue(http.StatusOK),
+		attrStatus:     attribute.BoolValue(false),
+	}
+	for key, value := range expected {
+		if attrs[key] != value {
+			t.Errorf("Expected %v to be %v, got %v", key, value.Emit(), attrs[key].Emit())
+		}
+	}
+
+	if event.Name() != "chatbase event" || event.Status().Code != codes.Error {
+		t.Errorf("Unexpected span %v with status %v", event.Name(), event.Status())
+	}
+	if attrs := spanAttributes(event); attrs[attrHTTPStatus] != attribute.IntValue(http.StatusInternalServerError) {
+		t.Errorf("Unexpected status code %v", attrs[attrHTTPStatus].Emit())
+	}
+}
+
+func TestTracer_NoResponse(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := New(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	req := &chatbase.APIRequest{Method: http.MethodPost, Kind: "message", Items: 1}
+	res, err := tracer.Intercept(context.Background(), req, func(ctx context.Context, req *chatbase.APIRequest) (*chatbase.APIResponse, error) {
+		return nil, nil
+	})
+	if res != nil || err != nil {
+		t.Errorf("Expected result to be passed on, got %v %v", res, err)
+	}
+	if spans := recorder.Ended(); len(spans) != 1 || spans[0].Status().Code != codes.Error {
+		t.Errorf("Expected failed span, got %v", spans)
+	}
+}
+
+func TestTracer_GlobalProvider(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	cached := func(ctx context.Context, req *chatbase.APIRequest, next chatbase.APICall) (*chatbase.APIResponse, error) {
+		return &chatbase.APIResponse{StatusCode: http.StatusOK, Body: []byte(`{"status":200}`)}, nil
+	}
+	chatbase.New("key", chatbase.WithInterceptors(New(nil).Intercept, cached)).Event("user", "intent").Submit()
+	if spans := recorder.Ended(); len(spans) != 1 || spans[0].Name() != "chatbase event" {
+		t.Errorf("Expected span using the global provider, got %v", spans)
+	}
+}
@@ -0,0 +1,108 @@
+package chatbase
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// transform creates modified copies of payloads before they are submitted.
+// Functions that are nil leave the respective items unchanged
+type transform struct {
+	message         func(Message) Message
+	event           func(Event) Event
+	facebookMessage func(FacebookMessage) (FacebookMessage, error)
+	requestResponse func(FacebookRequestResponse) (FacebookRequestResponse, error)
+}
+
+// apply returns a modified copy of the given payload, the payload
+// itself is not modified. Unknown payloads are returned as is
+func (t transform) apply(v interface{}) (interface{}, error) {
+	switch p := v.(type) {
+	case *Message:
+		if t.message == nil {
+			return v, nil
+		}
+		m := t.message(*p)
+		return &m, nil
+	case *Messages:
+		if t.message == nil {
+			return v, nil
+		}
+		messages := make(Messages, len(*p))
+		for i, m := range *p {
+			messages[i] = t.message(m)
+		}
+		return &messages, nil
+	case *Event:
+		if t.event == nil {
+			return v, nil
+		}
+		e := t.event(*p)
+		return &e, nil
+	case *Events:
+		if t.event == nil {
+			return v, nil
+		}
+		events := make(Events, len(*p))
+		for i, e := range *p {
+			events[i] = t.event(e)
+		}
+		return &events, nil
+	case *FacebookMessage:
+		if t.facebookMessage == nil {
+			return v, nil
+		}
+		f, err := t.facebookMessage(*p)
+		return &f, err
+	case *FacebookMessages:
+		if t.facebookMessage == nil {
+			return v, nil
+		}
+		messages := make(FacebookMessages, len(*p))
+		for i, f := range *p {
+			transformed, err := t.facebookMessage(f)
+			if err != nil {
+				return nil, err
+			}
+			messages[i] = transformed
+		}
+		return &messages, nil
+	case *FacebookRequestResponse:
+		if t.requestResponse == nil {
+			return v, nil
+		}
+		f, err := t.requestResponse(*p)
+		return &f, err
+	case *FacebookRequestResponses:
+		if t.requestResponse == nil {
+			return v, nil
+		}
+		pairs := make(FacebookRequestResponses, len(*p))
+		for i, f := range *p {
+			transformed, err := t.requestResponse(f)
+			if err != nil {
+				return nil, err
+			}
+			pairs[i] = transformed
+		}
+		return &pairs, nil
+	}
+	return v, nil
+}
+
+// genericJSON returns a copy of v consisting of maps, slices and
+// primitive values only. Numbers are kept as json.Number so they
+// survive being encoded again without losing precision
+func genericJSON(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
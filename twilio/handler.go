@@ -0,0 +1,102 @@
+package twilio
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+// Handler wraps the handler of a Twilio messaging webhook. It validates the
+// request signature, records the inbound message and the TwiML reply. All
+// requests are rejected when AuthToken is empty
+type Handler struct {
+	Client    *chatbase.Client
+	AuthToken string
+	Next      http.Handler
+	// URL is the public URL of the webhook as configured in Twilio. It needs
+	// to be set when the handler is running behind a proxy, otherwise it is
+	// derived from the request
+	URL string
+	// HashKey is used for hashing phone numbers. Messages are not recorded
+	// when it is empty
+	HashKey []byte
+	// ErrorHandler is called when messages cannot be recorded. Errors are
+	// discarded when it is nil
+	ErrorHandler func(error)
+}
+
+// NewHandler returns a new Handler using the given client, auth token,
+// key for hashing phone numbers and handler
+func NewHandler(client *chatbase.Client, authToken string, hashKey []byte, next http.Handler) *Handler {
+	return &Handler{
+		Client:    client,
+		AuthToken: authToken,
+		HashKey:   hashKey,
+		Next:      next,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.AuthToken == "" {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	body, bodyErr := ioutil.ReadAll(r.Body)
+	if bodyErr != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	params, paramsErr := url.ParseQuery(string(body))
+	if paramsErr != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	if err := ValidateSignature(h.AuthToken, h.webhookURL(r), params, r.Header.Get("X-Twilio-Signature")); err != nil {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	userMessage, userErr := UserMessage(h.Client, params, h.HashKey)
+	if userErr != nil {
+		h.handleError(userErr)
+		h.Next.ServeHTTP(w, r)
+		return
+	}
+
+	recorder := chatbase.NewResponseRecorder(w, 0)
+	h.Next.ServeHTTP(recorder, r)
+
+	messages := chatbase.Messages{}
+	messages.Append(userMessage)
+	if body := recorder.Body(); len(body) > 0 {
+		replies, err := AgentMessages(h.Client, userMessage.UserID, userMessage.Platform, body)
+		if err != nil {
+			h.handleError(err)
+		}
+		for i := range replies {
+			messages.Append(&replies[i])
+		}
+	}
+	chatbase.SubmitAsync(h.ErrorHandler, &messages)
+}
+
+func (h *Handler) webhookURL(r *http.Request) string {
+	if h.URL != "" {
+		return h.URL
+	}
+	scheme := "https"
+	if r.TLS == nil {
+		scheme = "http"
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+func (h *Handler) handleError(err error) {
+	if err != nil && h.ErrorHandler != nil {
+		h.ErrorHandler(err)
+	}
+}
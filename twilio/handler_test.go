@@ -0,0 +1,103 @@
+package twilio
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (r roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r(req)
+}
+
+func TestHandler(t *testing.T) {
+	defer chatbase.SetAPITransport(nil)
+	submitted := make(chan chatbase.Messages, 1)
+	chatbase.SetAPITransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var payload struct {
+			Messages chatbase.Messages `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		submitted <- payload.Messages
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"all_succeeded":true,"status":200}`)),
+		}, nil
+	}))
+
+	body := url.Values{"From": {"whatsapp:+14158675310"}, "Body": {"Hello"}}.Encode()
+	tests := []struct {
+		name             string
+		url              string
+		signature        string
+		expectedCode     int
+		expectedMessages []string
+	}{
+		{"derived url", "", sign("token", "http://example.net/sms?x=1", "BodyHelloFromwhatsapp:+14158675310"), http.StatusOK, []string{"user:Hello", "agent:Hi!"}},
+		{"configured url", "https://public.example.net/sms", sign("token", "https://public.example.net/sms", "BodyHelloFromwhatsapp:+14158675310"), http.StatusOK, []string{"user:Hello", "agent:Hi!"}},
+		{"bad signature", "", sign("token", "http://example.net/other", "BodyHelloFromwhatsapp:+14158675310"), http.StatusForbidden, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := NewHandler(chatbase.New("key"), "token", []byte("secret"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseForm(); err != nil || r.PostForm.Get("Body") != "Hello" {
+					t.Errorf("Expected form to be passed on, got %v", r.PostForm)
+				}
+				w.Header().Set("Content-Type", "text/xml")
+				w.Write([]byte(`<Response><Message>Hi!</Message></Response>`))
+			}))
+			h.URL = test.url
+			req := httptest.NewRequest(http.MethodPost, "http://example.net/sms?x=1", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("X-Twilio-Signature", test.signature)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != test.expectedCode {
+				t.Errorf("Expected status %v, got %v", test.expectedCode, rec.Code)
+			}
+			if test.expectedMessages == nil {
+				return
+			}
+			select {
+			case messages := <-submitted:
+				var result []string
+				for _, m := range messages {
+					if m.Platform != chatbase.PlatformWhatsApp || m.UserID != HashNumber([]byte("secret"), "+14158675310") {
+						t.Errorf("Unexpected message %#v", m)
+					}
+					result = append(result, string(m.Type)+":"+m.Message)
+				}
+				if strings.Join(result, ",") != strings.Join(test.expectedMessages, ",") {
+					t.Errorf("Expected %v, got %v", test.expectedMessages, result)
+				}
+			case <-time.After(time.Second):
+				t.Error("Expected messages to be submitted")
+			}
+		})
+	}
+}
+
+func TestHandler_EmptyAuthToken(t *testing.T) {
+	called := false
+	h := NewHandler(chatbase.New("key"), "", []byte("secret"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	body := url.Values{"From": {"+14158675310"}, "Body": {"Hello"}}.Encode()
+	req := httptest.NewRequest(http.MethodPost, "http://example.net/sms", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Twilio-Signature", sign("", "http://example.net/sms", "BodyHelloFrom+14158675310"))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden || called {
+		t.Errorf("Expected payload signed with empty token to be rejected, got %v", rec.Code)
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Package twilio records Twilio SMS and WhatsApp conversations in Chatbase.
+
+Inbound messages are parsed from Twilio's form encoded webhooks, replies are
+parsed from the TwiML returned by the webhook. Phone numbers are never sent
+to Chatbase, hashed values are used as user ids instead:
+
+	client := chatbase.New("MY-API-KEY")
+	handler := twilio.NewHandler(client, "TWILIO-AUTH-TOKEN", []byte("SECRET-HASH-KEY"), smsHandler)
+	http.Handle("/sms", handler)
+*/
+package twilio
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+const whatsAppPrefix = "whatsapp:"
+
+// ErrBadSignature is returned when the X-Twilio-Signature header does
+// not match the request
+var ErrBadSignature = errors.New("request signature does not match")
+
+// ErrMissingSender is returned for webhooks without a sender
+var ErrMissingSender = errors.New("webhook does not contain a sender")
+
+// ErrMissingHashKey is returned when phone numbers would be hashed without
+// a key, which would allow recovering them by hashing all possible numbers
+var ErrMissingHashKey = errors.New("hashing phone numbers requires a key")
+
+// ValidateSignature checks the X-Twilio-Signature value of a request made to
+// the given URL using the posted form values
+func ValidateSignature(authToken, rawURL string, params url.Values, signature string) error {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var data bytes.Buffer
+	data.WriteString(rawURL)
+	for _, key := range keys {
+		for _, value := range params[key] {
+			data.WriteString(key)
+			data.WriteString(value)
+		}
+	}
+	mac := hmac.New(sha1.New, []byte(authToken))
+	mac.Write(data.Bytes())
+	expected, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac.Sum(nil), expected) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// HashNumber returns the hex encoded HMAC-SHA256 of the given phone number
+// using key. Channel prefixes like "whatsapp:" are removed before hashing
+// so the same number results in the same id on all channels
+func HashNumber(key []byte, number string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strings.TrimPrefix(number, whatsAppPrefix)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Platform returns chatbase.PlatformWhatsApp for WhatsApp addresses
+// and chatbase.PlatformSMS for any other number
+func Platform(address string) string {
+	if strings.HasPrefix(address, whatsAppPrefix) {
+		return chatbase.PlatformWhatsApp
+	}
+	return chatbase.PlatformSMS
+}
+
+// UserMessage converts the form values of an inbound message webhook
+// into a user message, hashing the sender's number using key. The key
+// must not be empty
+func UserMessage(client *chatbase.Client, params url.Values, key []byte) (*chatbase.Message, error) {
+	if len(key) == 0 {
+		return nil, ErrMissingHashKey
+	}
+	from := params.Get("From")
+	if from == "" {
+		return nil, ErrMissingSender
+	}
+	return client.UserMessage(HashNumber(key, from), Platform(from)).
+		SetMessage(params.Get("Body")), nil
+}
+
+// AgentMessages parses the messages contained in the given TwiML response
+// and converts them into agent messages addressed to the given user
+func AgentMessages(client *chatbase.Client, userID, platform string, twiml []byte) (chatbase.Messages, error) {
+	messages := chatbase.Messages{}
+	decoder := xml.NewDecoder(bytes.NewReader(twiml))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return messages, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "Message" {
+			continue
+		}
+		var element struct {
+			Text string `xml:",chardata"`
+			Body string `xml:"Body"`
+		}
+		if err := decoder.DecodeElement(&element, &start); err != nil {
+			return nil, err
+		}
+		text := element.Body
+		if text == "" {
+			text = element.Text
+		}
+		messages.Append(client.AgentMessage(userID, platform).SetMessage(strings.TrimSpace(text)))
+	}
+}
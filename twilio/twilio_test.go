@@ -0,0 +1,135 @@
+package twilio
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/url"
+	"reflect"
+	"testing"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+func sign(authToken, rawURL string, data string) string {
+	mac := hmac.New(sha1.New, []byte(authToken))
+	mac.Write([]byte(rawURL + data))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestValidateSignature(t *testing.T) {
+	params := url.Values{"From": {"+14158675310"}, "Body": {"Hello"}, "To": {"+14158675309"}}
+	// parameters are sorted by key before being appended to the URL
+	valid := sign("token", "https://example.net/sms", "BodyHelloFrom+14158675310To+14158675309")
+	tests := []struct {
+		name      string
+		token     string
+		url       string
+		signature string
+		expected  error
+	}{
+		{"default", "token", "https://example.net/sms", valid, nil},
+		{"bad token", "nope", "https://example.net/sms", valid, ErrBadSignature},
+		{"bad url", "token", "https://example.net/other", valid, ErrBadSignature},
+		{"bad encoding", "token", "https://example.net/sms", "%%%", ErrBadSignature},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := ValidateSignature(test.token, test.url, params, test.signature); err != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestHashNumber(t *testing.T) {
+	a := HashNumber([]byte("key"), "+14158675310")
+	if a != HashNumber([]byte("key"), "whatsapp:+14158675310") {
+		t.Error("Expected channel prefix to be ignored")
+	}
+	if a == HashNumber([]byte("other"), "+14158675310") {
+		t.Error("Expected key to be used")
+	}
+	if len(a) != 64 {
+		t.Errorf("Unexpected hash %v", a)
+	}
+}
+
+func TestUserMessage(t *testing.T) {
+	oldTimeStamp := chatbase.TimeStamp
+	defer func() { chatbase.TimeStamp = oldTimeStamp }()
+	chatbase.TimeStamp = func() int64 { return 998877 }
+	client := chatbase.New("key")
+
+	tests := []struct {
+		name        string
+		params      url.Values
+		expectError bool
+		expected    *chatbase.Message
+	}{
+		{
+			"sms",
+			url.Values{"From": {"+14158675310"}, "Body": {"Hello"}},
+			false,
+			&chatbase.Message{APIKey: "key", Type: chatbase.UserType, UserID: HashNumber([]byte("secret"), "+14158675310"), TimeStamp: 998877, Platform: "SMS", Message: "Hello"},
+		},
+		{
+			"whatsapp",
+			url.Values{"From": {"whatsapp:+14158675310"}, "Body": {"Hello"}},
+			false,
+			&chatbase.Message{APIKey: "key", Type: chatbase.UserType, UserID: HashNumber([]byte("secret"), "+14158675310"), TimeStamp: 998877, Platform: "WhatsApp", Message: "Hello"},
+		},
+		{"no sender", url.Values{"Body": {"Hello"}}, true, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := UserMessage(client, test.params, []byte("secret"))
+			if test.expectError != (err != nil) {
+				t.Errorf("Unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(test.expected, result) {
+				t.Errorf("Expected %#v, got %#v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestUserMessage_MissingKey(t *testing.T) {
+	params := url.Values{"From": {"+14158675310"}, "Body": {"Hello"}}
+	if _, err := UserMessage(chatbase.New("key"), params, nil); err != ErrMissingHashKey {
+		t.Errorf("Expected ErrMissingHashKey, got %v", err)
+	}
+}
+
+func TestAgentMessages(t *testing.T) {
+	client := chatbase.New("key")
+	tests := []struct {
+		name        string
+		twiml       string
+		expectError bool
+		expected    []string
+	}{
+		{"plain", `<?xml version="1.0" encoding="UTF-8"?><Response><Message>Hi there!</Message></Response>`, false, []string{"Hi there!"}},
+		{"nested body", `<Response><Message><Body>Look</Body><Media>https://example.net/cat.png</Media></Message><Message>Second</Message></Response>`, false, []string{"Look", "Second"}},
+		{"empty response", `<Response/>`, false, nil},
+		{"bad xml", `<Response><Message>`, true, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			messages, err := AgentMessages(client, "user", chatbase.PlatformSMS, []byte(test.twiml))
+			if test.expectError != (err != nil) {
+				t.Errorf("Unexpected error %v", err)
+			}
+			var texts []string
+			for _, m := range messages {
+				if m.Type != chatbase.AgentType || m.UserID != "user" {
+					t.Errorf("Unexpected message %#v", m)
+				}
+				texts = append(texts, m.Message)
+			}
+			if !reflect.DeepEqual(test.expected, texts) {
+				t.Errorf("Expected %v, got %v", test.expected, texts)
+			}
+		})
+	}
+}
@@ -17,6 +17,8 @@ type Update struct {
 	NotHandled string    `json:"not_handled,omitempty"`
 	Feedback   string    `json:"feedback,omitempty"`
 	Version    string    `json:"version,omitempty"`
+
+	config *config
 }
 
 // SetIntent adds an optional "intent" value to an update
@@ -48,27 +50,14 @@ func (u *Update) SetVersion(v string) *Update {
 
 // Submit tries to deliver the update to Chatbase
 func (u *Update) Submit() (*UpdateResponse, error) {
-	return newUpdateResponse(func() (io.ReadCloser, error) {
-		ep, epErr := augmentURL(updateEndpoint, map[string]string{
-			"api_key":    u.APIKey,
-			"message_id": u.MessageID.String(),
-		})
-		if epErr != nil {
-			return nil, epErr
-		}
-		body, bodyErr := apiPut(ep, u)
-		if bodyErr != nil {
-			return nil, bodyErr
-		}
-		return body, nil
-	})
+	return u.submit(context.Background())
 }
 
 // SubmitWithContext tries to deliver the update to Chatbase while
 // considering the given context's deadline
 func (u *Update) SubmitWithContext(ctx context.Context) (*UpdateResponse, error) {
 	data, err := resultWithContext(ctx, func() (interface{}, error) {
-		return u.Submit()
+		return u.submit(ctx)
 	})
 	if err != nil {
 		return nil, err
@@ -79,6 +68,23 @@ func (u *Update) SubmitWithContext(ctx context.Context) (*UpdateResponse, error)
 	return nil, errBadData
 }
 
+func (u *Update) submit(ctx context.Context) (*UpdateResponse, error) {
+	return newUpdateResponse(func() (io.ReadCloser, error) {
+		ep, epErr := augmentURL(updateEndpoint, map[string]string{
+			"api_key":    u.APIKey,
+			"message_id": u.MessageID.String(),
+		})
+		if epErr != nil {
+			return nil, epErr
+		}
+		body, bodyErr := apiPut(ctx, u.config, ep, u)
+		if bodyErr != nil {
+			return nil, bodyErr
+		}
+		return body, nil
+	})
+}
+
 // UpdateResponse describes a Chatbase response to an update submission
 type UpdateResponse struct {
 	Error   []string `json:"error"`
@@ -0,0 +1,63 @@
+package viber
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+// Handler wraps the handler of a Viber webhook. It verifies the signature
+// of each callback and records messages and events before passing the
+// request on. All requests are rejected when AuthToken is empty
+type Handler struct {
+	Client    *chatbase.Client
+	AuthToken string
+	Next      http.Handler
+	// ErrorHandler is called when a callback cannot be recorded. Errors are
+	// discarded when it is nil
+	ErrorHandler func(error)
+}
+
+// NewHandler returns a new Handler using the given client, auth token
+// and handler
+func NewHandler(client *chatbase.Client, authToken string, next http.Handler) *Handler {
+	return &Handler{
+		Client:    client,
+		AuthToken: authToken,
+		Next:      next,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.AuthToken == "" {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	body, bodyErr := ioutil.ReadAll(r.Body)
+	if bodyErr != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	if err := VerifySignature(h.AuthToken, body, r.Header.Get("X-Viber-Content-Signature")); err != nil {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	var callback Callback
+	if err := json.Unmarshal(body, &callback); err != nil {
+		h.handleError(err)
+	} else if message, event, err := Convert(h.Client, &callback); err == nil {
+		chatbase.SubmitAsync(h.ErrorHandler, message, event)
+	}
+	h.Next.ServeHTTP(w, r)
+}
+
+func (h *Handler) handleError(err error) {
+	if err != nil && h.ErrorHandler != nil {
+		h.ErrorHandler(err)
+	}
+}
@@ -0,0 +1,115 @@
+package viber
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (r roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r(req)
+}
+
+func captureRequests() chan map[string]interface{} {
+	submitted := make(chan map[string]interface{}, 1)
+	chatbase.SetAPITransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var data map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&data)
+		submitted <- data
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"status":200}`)),
+		}, nil
+	}))
+	return submitted
+}
+
+func TestHandler(t *testing.T) {
+	defer chatbase.SetAPITransport(nil)
+	submitted := captureRequests()
+
+	tests := []struct {
+		name           string
+		body           string
+		token          string
+		expectedCode   int
+		expectedFields map[string]interface{}
+	}{
+		{
+			"message",
+			`{"event":"message","timestamp":1,"sender":{"id":"U1"},"message":{"type":"text","text":"Hello"}}`,
+			"token",
+			http.StatusOK,
+			map[string]interface{}{"user_id": "U1", "message": "Hello", "type": "user"},
+		},
+		{
+			"subscribed",
+			`{"event":"subscribed","timestamp":1,"user":{"id":"U1"}}`,
+			"token",
+			http.StatusOK,
+			map[string]interface{}{"user_id": "U1", "intent": "subscribed"},
+		},
+		{"seen", `{"event":"seen","timestamp":1,"user_id":"U1"}`, "token", http.StatusOK, nil},
+		{"bad signature", `{"event":"message","timestamp":1,"sender":{"id":"U1"}}`, "nope", http.StatusForbidden, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			called := false
+			h := NewHandler(chatbase.New("key"), "token", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := ioutil.ReadAll(r.Body)
+				called = string(b) == test.body
+			}))
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(test.body))
+			req.Header.Set("X-Viber-Content-Signature", sign(test.token, test.body))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != test.expectedCode {
+				t.Errorf("Expected status %v, got %v", test.expectedCode, rec.Code)
+			}
+			if called != (test.expectedCode == http.StatusOK) {
+				t.Errorf("Unexpected call of next handler: %v", called)
+			}
+			if test.expectedFields == nil {
+				select {
+				case data := <-submitted:
+					t.Errorf("Unexpected submission %v", data)
+				case <-time.After(50 * time.Millisecond):
+				}
+				return
+			}
+			select {
+			case data := <-submitted:
+				for key, value := range test.expectedFields {
+					if data[key] != value {
+						t.Errorf("Expected %v to be %v, got %v", key, value, data[key])
+					}
+				}
+			case <-time.After(time.Second):
+				t.Error("Expected data to be submitted")
+			}
+		})
+	}
+}
+
+func TestHandler_EmptyAuthToken(t *testing.T) {
+	called := false
+	h := NewHandler(chatbase.New("key"), "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	body := `{"event":"message","timestamp":1,"sender":{"id":"u1"},"message":{"type":"text","text":"hi"}}`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("X-Viber-Content-Signature", sign("", body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden || called {
+		t.Errorf("Expected payload signed with empty token to be rejected, got %v", rec.Code)
+	}
+}
@@ -0,0 +1,86 @@
+package viber
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+// Transport is an http.RoundTripper for the client used for calling the
+// Viber REST API. Successful send_message calls are recorded as agent
+// messages, any other request is passed through unchanged
+type Transport struct {
+	Client *chatbase.Client
+	// Base is the RoundTripper used for performing the actual requests,
+	// http.DefaultTransport is used when it is nil
+	Base http.RoundTripper
+	// ErrorHandler is called when a call cannot be recorded. Errors are
+	// discarded when it is nil
+	ErrorHandler func(error)
+}
+
+// NewTransport returns a new Transport using the given client and base transport
+func NewTransport(client *chatbase.Client, base http.RoundTripper) *Transport {
+	return &Transport{
+		Client: client,
+		Base:   base,
+	}
+}
+
+// RoundTrip performs the request and records send_message calls
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, "/send_message") || req.Body == nil {
+		return t.base().RoundTrip(req)
+	}
+
+	requestBody, requestErr := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if requestErr != nil {
+		return nil, requestErr
+	}
+	req = req.Clone(req.Context())
+	req.Body = ioutil.NopCloser(bytes.NewReader(requestBody))
+
+	res, err := t.base().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	responseBody, responseErr := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if responseErr != nil {
+		return nil, responseErr
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(responseBody))
+
+	var send SendMessage
+	if err := json.Unmarshal(requestBody, &send); err != nil {
+		t.handleError(err)
+		return res, nil
+	}
+	var result SendMessageResponse
+	if err := json.Unmarshal(responseBody, &result); err != nil {
+		t.handleError(err)
+		return res, nil
+	}
+	if result.Status == 0 {
+		chatbase.SubmitAsync(t.ErrorHandler, AgentMessage(t.Client, &send))
+	}
+	return res, nil
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+func (t *Transport) handleError(err error) {
+	if err != nil && t.ErrorHandler != nil {
+		t.ErrorHandler(err)
+	}
+}
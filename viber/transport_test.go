@@ -0,0 +1,103 @@
+package viber
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+func TestTransport(t *testing.T) {
+	defer chatbase.SetAPITransport(nil)
+	submitted := captureRequests()
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		if strings.Contains(string(b), "fail") {
+			w.Write([]byte(`{"status":6,"status_message":"notSubscribed"}`))
+			return
+		}
+		w.Write([]byte(`{"status":0,"status_message":"ok","message_token":5741311803571721087}`))
+	}))
+	defer api.Close()
+
+	httpClient := &http.Client{Transport: NewTransport(chatbase.New("key"), nil)}
+	tests := []struct {
+		name            string
+		path            string
+		body            string
+		expectedMessage string
+	}{
+		{"default", "/pa/send_message", `{"receiver":"U1","type":"text","text":"Hi there"}`, "U1:Hi there"},
+		{"failed call", "/pa/send_message", `{"receiver":"U1","type":"text","text":"fail"}`, ""},
+		{"other method", "/pa/get_account_info", `{}`, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := httpClient.Post(api.URL+test.path, "application/json", strings.NewReader(test.body))
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			b, _ := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+			if !strings.Contains(string(b), `"status"`) {
+				t.Errorf("Unexpected response body %s", b)
+			}
+			if test.expectedMessage == "" {
+				select {
+				case data := <-submitted:
+					t.Errorf("Unexpected submission %v", data)
+				case <-time.After(50 * time.Millisecond):
+				}
+				return
+			}
+			select {
+			case data := <-submitted:
+				if s := data["user_id"].(string) + ":" + data["message"].(string); s != test.expectedMessage || data["type"] != "agent" {
+					t.Errorf("Expected %v, got %v", test.expectedMessage, data)
+				}
+			case <-time.After(time.Second):
+				t.Error("Expected message to be submitted")
+			}
+		})
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestTransport_ResponseError(t *testing.T) {
+	transport := NewTransport(chatbase.New("key"), roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(failingReader{})}, nil
+	}))
+	req := httptest.NewRequest(http.MethodPost, "https://example.net/pa/send_message", strings.NewReader(`{}`))
+	res, err := transport.RoundTrip(req)
+	if res != nil || err == nil {
+		t.Errorf("Expected error without response, got %v %v", res, err)
+	}
+}
+
+func TestTransport_Request(t *testing.T) {
+	transport := NewTransport(chatbase.New("key"), roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if b, _ := ioutil.ReadAll(req.Body); string(b) != `{"receiver":"u1"}` {
+			t.Errorf("Expected body to be passed on, got %s", b)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(`{"status":1}`))}, nil
+	}))
+	req := httptest.NewRequest(http.MethodPost, "https://chatapi.viber.com/pa/send_message", strings.NewReader(`{"receiver":"u1"}`))
+	body := req.Body
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if req.Body != body {
+		t.Error("Expected request of the caller not to be modified")
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Package viber records conversations of Viber bots in Chatbase.
+
+Incoming messages are recorded as user messages, subscription changes and
+started conversations as events. Messages sent using the send_message API
+are recorded as agent messages by Transport:
+
+	client := chatbase.New("MY-API-KEY")
+	http.Handle("/viber", viber.NewHandler(client, "AUTH-TOKEN", botHandler))
+
+	// record replies sent by the bot
+	viberClient := &http.Client{Transport: viber.NewTransport(client, nil)}
+*/
+package viber
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+// Callback events that are recorded
+const (
+	MessageEvent             = "message"
+	SubscribedEvent          = "subscribed"
+	UnsubscribedEvent        = "unsubscribed"
+	ConversationStartedEvent = "conversation_started"
+)
+
+// ErrBadSignature is returned when a callback's signature does not match
+var ErrBadSignature = errors.New("request signature does not match")
+
+// ErrUnsupportedEvent is returned for callbacks that are not recorded
+var ErrUnsupportedEvent = errors.New("callback event is not supported")
+
+// Callback is a callback sent to a bot's webhook
+type Callback struct {
+	Event        string   `json:"event"`
+	Timestamp    int64    `json:"timestamp"`
+	MessageToken int64    `json:"message_token,omitempty"`
+	Sender       *User    `json:"sender,omitempty"`
+	User         *User    `json:"user,omitempty"`
+	UserID       string   `json:"user_id,omitempty"`
+	Message      *Message `json:"message,omitempty"`
+	Type         string   `json:"type,omitempty"`
+	Context      string   `json:"context,omitempty"`
+	Subscribed   bool     `json:"subscribed,omitempty"`
+}
+
+// User is a Viber user
+type User struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// Message is a message received by or sent from a bot
+type Message struct {
+	Type         string `json:"type"`
+	Text         string `json:"text,omitempty"`
+	Media        string `json:"media,omitempty"`
+	TrackingData string `json:"tracking_data,omitempty"`
+}
+
+// SendMessage is the body of a call to the send_message API
+type SendMessage struct {
+	Receiver     string `json:"receiver"`
+	Type         string `json:"type"`
+	Text         string `json:"text,omitempty"`
+	Media        string `json:"media,omitempty"`
+	TrackingData string `json:"tracking_data,omitempty"`
+	Sender       *User  `json:"sender,omitempty"`
+}
+
+// SendMessageResponse is the response to a call to the send_message API
+type SendMessageResponse struct {
+	Status        int    `json:"status"`
+	StatusMessage string `json:"status_message"`
+	MessageToken  int64  `json:"message_token,omitempty"`
+}
+
+// VerifySignature checks the value of the X-Viber-Content-Signature header
+// against the given body and the bot's auth token
+func VerifySignature(authToken string, body []byte, signature string) error {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return ErrBadSignature
+	}
+	mac := hmac.New(sha256.New, []byte(authToken))
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// userID returns the id of the user that triggered the callback, which
+// is stored in different fields depending on the kind of event
+func (c *Callback) userID() string {
+	switch {
+	case c.Sender != nil:
+		return c.Sender.ID
+	case c.User != nil:
+		return c.User.ID
+	}
+	return c.UserID
+}
+
+// Convert maps a callback to either a user message or an event. Delivery
+// receipts and other callbacks return ErrUnsupportedEvent
+func Convert(client *chatbase.Client, c *Callback) (*chatbase.Message, *chatbase.Event, error) {
+	userID := c.userID()
+	if userID == "" {
+		return nil, nil, ErrUnsupportedEvent
+	}
+	switch c.Event {
+	case MessageEvent:
+		m := client.UserMessage(userID, chatbase.PlatformViber).
+			SetTimeStamp(c.Timestamp).
+			SetSessionID(userID)
+		if c.Message != nil {
+			m.SetMessage(c.Message.Text)
+		}
+		return m, nil, nil
+	case SubscribedEvent, UnsubscribedEvent, ConversationStartedEvent:
+		e := client.Event(userID, c.Event).
+			SetPlatform(chatbase.PlatformViber).
+			SetTimeStamp(c.Timestamp)
+		if c.Event == ConversationStartedEvent && c.Context != "" {
+			e.AddProperty("context", c.Context)
+		}
+		return nil, e, nil
+	}
+	return nil, nil, ErrUnsupportedEvent
+}
+
+// AgentMessage converts a send_message call into an agent message
+func AgentMessage(client *chatbase.Client, s *SendMessage) *chatbase.Message {
+	return client.AgentMessage(s.Receiver, chatbase.PlatformViber).
+		SetMessage(s.Text).
+		SetSessionID(s.Receiver)
+}
@@ -0,0 +1,99 @@
+package viber
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+func sign(token, body string) string {
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	tests := []struct {
+		name      string
+		signature string
+		expected  error
+	}{
+		{"default", sign("token", "body"), nil},
+		{"bad token", sign("nope", "body"), ErrBadSignature},
+		{"bad encoding", "xyz", ErrBadSignature},
+		{"empty", "", ErrBadSignature},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := VerifySignature("token", []byte("body"), test.signature); err != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	client := chatbase.New("key")
+	context, _ := chatbase.NewEventProperty("context", "ad-campaign")
+	tests := []struct {
+		name            string
+		input           string
+		expectError     bool
+		expectedMessage *chatbase.Message
+		expectedEvent   *chatbase.Event
+	}{
+		{
+			"message",
+			`{"event":"message","timestamp":1457764197627,"message_token":4912661846655238145,"sender":{"id":"U1=","name":"John"},"message":{"type":"text","text":"Hello"}}`,
+			false,
+			&chatbase.Message{APIKey: "key", Type: chatbase.UserType, UserID: "U1=", TimeStamp: 1457764197627, Platform: "Viber", Message: "Hello", SessionID: "U1="},
+			nil,
+		},
+		{
+			"subscribed",
+			`{"event":"subscribed","timestamp":1457764197627,"user":{"id":"U1="}}`,
+			false,
+			nil,
+			&chatbase.Event{APIKey: "key", UserID: "U1=", Intent: SubscribedEvent, TimeStamp: 1457764197627, Platform: "Viber"},
+		},
+		{
+			"unsubscribed",
+			`{"event":"unsubscribed","timestamp":1457764197627,"user_id":"U1="}`,
+			false,
+			nil,
+			&chatbase.Event{APIKey: "key", UserID: "U1=", Intent: UnsubscribedEvent, TimeStamp: 1457764197627, Platform: "Viber"},
+		},
+		{
+			"conversation started",
+			`{"event":"conversation_started","timestamp":1457764197627,"type":"open","context":"ad-campaign","user":{"id":"U1="}}`,
+			false,
+			nil,
+			&chatbase.Event{APIKey: "key", UserID: "U1=", Intent: ConversationStartedEvent, TimeStamp: 1457764197627, Platform: "Viber", Properties: []chatbase.EventProperty{context}},
+		},
+		{"delivered", `{"event":"delivered","timestamp":1457764197627,"user_id":"U1="}`, true, nil, nil},
+		{"webhook", `{"event":"webhook","timestamp":1457764197627}`, true, nil, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var c Callback
+			if err := json.Unmarshal([]byte(test.input), &c); err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			message, event, err := Convert(client, &c)
+			if test.expectError != (err != nil) {
+				t.Errorf("Unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(test.expectedMessage, message) {
+				t.Errorf("Expected %#v, got %#v", test.expectedMessage, message)
+			}
+			if !reflect.DeepEqual(test.expectedEvent, event) {
+				t.Errorf("Expected %#v, got %#v", test.expectedEvent, event)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package wechat
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+// Handler wraps the handler of an official account's server. It verifies
+// the signature of each request, answers URL verification requests and
+// records incoming messages as well as passive replies. All requests are
+// rejected when Token is empty
+type Handler struct {
+	Client *chatbase.Client
+	Token  string
+	Next   http.Handler
+	// ErrorHandler is called when a message cannot be recorded. Errors are
+	// discarded when it is nil
+	ErrorHandler func(error)
+}
+
+// NewHandler returns a new Handler using the given client, token and handler
+func NewHandler(client *chatbase.Client, token string, next http.Handler) *Handler {
+	return &Handler{
+		Client: client,
+		Token:  token,
+		Next:   next,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.Token == "" {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	query := r.URL.Query()
+	if err := VerifySignature(h.Token, query.Get("timestamp"), query.Get("nonce"), query.Get("signature")); err != nil {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	if r.Method == http.MethodGet {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(query.Get("echostr")))
+		return
+	}
+
+	body, bodyErr := ioutil.ReadAll(r.Body)
+	if bodyErr != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	var incoming Message
+	if err := xml.Unmarshal(body, &incoming); err != nil {
+		h.handleError(err)
+		h.Next.ServeHTTP(w, r)
+		return
+	}
+
+	messages := chatbase.Messages{}
+	events := chatbase.Events{}
+	if message, event, err := Convert(h.Client, &incoming); err == nil {
+		if message != nil {
+			messages.Append(message)
+		}
+		if event != nil {
+			events.Append(event)
+		}
+	}
+
+	recorder := chatbase.NewResponseRecorder(w, 0)
+	h.Next.ServeHTTP(recorder, r)
+
+	if reply, ok := parseReply(recorder.Body()); ok {
+		messages.Append(AgentMessage(h.Client, reply))
+	}
+	chatbase.SubmitAsync(h.ErrorHandler, &messages, &events)
+}
+
+func (h *Handler) handleError(err error) {
+	if err != nil && h.ErrorHandler != nil {
+		h.ErrorHandler(err)
+	}
+}
+
+// parseReply reads a passive reply from the response body. Empty bodies and
+// the "success" acknowledgement do not contain a reply
+func parseReply(body []byte) (*Message, bool) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || string(body) == "success" {
+		return nil, false
+	}
+	var reply Message
+	if err := xml.Unmarshal(body, &reply); err != nil || reply.ToUserName == "" {
+		return nil, false
+	}
+	return &reply, true
+}
@@ -0,0 +1,124 @@
+package wechat
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (r roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r(req)
+}
+
+func signedURL(token string) string {
+	return "/?" + url.Values{
+		"signature": {sign(token, "1348831860", "nonce")},
+		"timestamp": {"1348831860"},
+		"nonce":     {"nonce"},
+		"echostr":   {"echo"},
+	}.Encode()
+}
+
+func TestHandler(t *testing.T) {
+	defer chatbase.SetAPITransport(nil)
+	submitted := make(chan string, 1)
+	chatbase.SetAPITransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var data struct {
+			Messages []chatbase.Message `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&data)
+		var summary []string
+		for _, m := range data.Messages {
+			summary = append(summary, string(m.Type)+":"+m.UserID+":"+m.Message)
+		}
+		submitted <- strings.Join(summary, ",")
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"all_succeeded":true,"status":200}`)),
+		}, nil
+	}))
+
+	incoming := `<xml><ToUserName>account</ToUserName><FromUserName>user</FromUserName><CreateTime>1348831860</CreateTime><MsgType>text</MsgType><Content>Hello</Content></xml>`
+	tests := []struct {
+		name             string
+		method           string
+		token            string
+		reply            string
+		expectedCode     int
+		expectedBody     string
+		expectedMessages string
+	}{
+		{"verification", http.MethodGet, "token", "", http.StatusOK, "echo", ""},
+		{
+			"reply",
+			http.MethodPost,
+			"token",
+			`<xml><ToUserName>user</ToUserName><FromUserName>account</FromUserName><CreateTime>1348831861</CreateTime><MsgType>text</MsgType><Content>Hi</Content></xml>`,
+			http.StatusOK,
+			"<xml>",
+			"user:user:Hello,agent:user:Hi",
+		},
+		{"acknowledgement", http.MethodPost, "token", "success", http.StatusOK, "success", "user:user:Hello"},
+		{"bad signature", http.MethodPost, "nope", "", http.StatusForbidden, "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := NewHandler(chatbase.New("key"), "token", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := ioutil.ReadAll(r.Body)
+				if string(b) != incoming {
+					t.Errorf("Unexpected body %s", b)
+				}
+				w.Write([]byte(test.reply))
+			}))
+			req := httptest.NewRequest(test.method, signedURL(test.token), strings.NewReader(incoming))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != test.expectedCode {
+				t.Errorf("Expected status %v, got %v", test.expectedCode, rec.Code)
+			}
+			if !strings.HasPrefix(rec.Body.String(), test.expectedBody) {
+				t.Errorf("Unexpected body %v", rec.Body.String())
+			}
+			if test.expectedMessages == "" {
+				select {
+				case s := <-submitted:
+					t.Errorf("Unexpected submission %v", s)
+				case <-time.After(50 * time.Millisecond):
+				}
+				return
+			}
+			select {
+			case s := <-submitted:
+				if s != test.expectedMessages {
+					t.Errorf("Expected %v, got %v", test.expectedMessages, s)
+				}
+			case <-time.After(time.Second):
+				t.Error("Expected messages to be submitted")
+			}
+		})
+	}
+}
+
+func TestHandler_EmptyToken(t *testing.T) {
+	called := false
+	h := NewHandler(chatbase.New("key"), "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		req := httptest.NewRequest(method, signedURL(""), strings.NewReader(`<xml><MsgType>text</MsgType></xml>`))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden || called {
+			t.Errorf("Expected %s request signed with empty token to be rejected, got %v", method, rec.Code)
+		}
+	}
+}
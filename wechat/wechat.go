@@ -0,0 +1,113 @@
+/*
+Package wechat records conversations of WeChat official accounts in Chatbase.
+
+Incoming messages are recorded as user messages and subscription changes as
+events. Passive replies written to the webhook's response are recorded as
+agent messages. Only plaintext mode is supported, messages encrypted using
+safe mode cannot be read:
+
+	client := chatbase.New("MY-API-KEY")
+	http.Handle("/wechat", wechat.NewHandler(client, "TOKEN", accountHandler))
+*/
+package wechat
+
+import (
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"sort"
+	"strings"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+// Message types and events that are recorded
+const (
+	TextMessage      = "text"
+	EventMessage     = "event"
+	SubscribeEvent   = "subscribe"
+	UnsubscribeEvent = "unsubscribe"
+)
+
+// ErrBadSignature is returned when a request's signature does not match
+var ErrBadSignature = errors.New("request signature does not match")
+
+// ErrUnsupportedMessage is returned for messages that are not recorded
+var ErrUnsupportedMessage = errors.New("message type is not supported")
+
+// Message is a message pushed to or passively returned by an
+// official account's server
+type Message struct {
+	XMLName      xml.Name `xml:"xml"`
+	ToUserName   string   `xml:"ToUserName"`
+	FromUserName string   `xml:"FromUserName"`
+	CreateTime   int64    `xml:"CreateTime"`
+	MsgType      string   `xml:"MsgType"`
+	Content      string   `xml:"Content,omitempty"`
+	MsgID        int64    `xml:"MsgId,omitempty"`
+	Recognition  string   `xml:"Recognition,omitempty"`
+	Event        string   `xml:"Event,omitempty"`
+	EventKey     string   `xml:"EventKey,omitempty"`
+}
+
+// VerifySignature checks the signature sent as query parameter against the
+// SHA1 hash of the sorted token, timestamp and nonce
+func VerifySignature(token, timestamp, nonce, signature string) error {
+	values := []string{token, timestamp, nonce}
+	sort.Strings(values)
+	sum := sha1.Sum([]byte(strings.Join(values, "")))
+	if subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(strings.ToLower(signature))) != 1 {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// Convert maps a message pushed by WeChat to either a user message or an
+// event. The user's OpenID is used as user and session id. Messages other
+// than text, voice with speech recognition and subscription events return
+// ErrUnsupportedMessage
+func Convert(client *chatbase.Client, m *Message) (*chatbase.Message, *chatbase.Event, error) {
+	if m.FromUserName == "" {
+		return nil, nil, ErrUnsupportedMessage
+	}
+	ts := m.CreateTime * 1000
+	switch {
+	case m.MsgType == TextMessage || m.Recognition != "":
+		text := m.Content
+		if text == "" {
+			text = m.Recognition
+		}
+		message := client.UserMessage(m.FromUserName, chatbase.PlatformWeChat).
+			SetMessage(text).
+			SetTimeStamp(ts).
+			SetSessionID(m.FromUserName)
+		return message, nil, nil
+	case m.MsgType == EventMessage && (m.Event == SubscribeEvent || m.Event == UnsubscribeEvent):
+		event := client.Event(m.FromUserName, m.Event).
+			SetPlatform(chatbase.PlatformWeChat).
+			SetTimeStamp(ts)
+		if m.EventKey != "" {
+			event.AddProperty("event_key", m.EventKey)
+		}
+		return nil, event, nil
+	}
+	return nil, nil, ErrUnsupportedMessage
+}
+
+// AgentMessage converts a passive reply into an agent message. Replies that
+// are not text messages are recorded using their type as message
+func AgentMessage(client *chatbase.Client, reply *Message) *chatbase.Message {
+	text := reply.Content
+	if reply.MsgType != TextMessage {
+		text = reply.MsgType
+	}
+	m := client.AgentMessage(reply.ToUserName, chatbase.PlatformWeChat).
+		SetMessage(text).
+		SetSessionID(reply.ToUserName)
+	if reply.CreateTime != 0 {
+		m.SetTimeStamp(reply.CreateTime * 1000)
+	}
+	return m
+}
@@ -0,0 +1,128 @@
+package wechat
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	chatbase "github.com/m90/go-chatbase/v2"
+)
+
+func sign(token, timestamp, nonce string) string {
+	values := []string{token, timestamp, nonce}
+	sort.Strings(values)
+	sum := sha1.Sum([]byte(strings.Join(values, "")))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestVerifySignature(t *testing.T) {
+	tests := []struct {
+		name      string
+		signature string
+		expected  error
+	}{
+		{"default", sign("token", "1348831860", "nonce"), nil},
+		{"uppercase", strings.ToUpper(sign("token", "1348831860", "nonce")), nil},
+		{"bad token", sign("nope", "1348831860", "nonce"), ErrBadSignature},
+		{"empty", "", ErrBadSignature},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := VerifySignature("token", "1348831860", "nonce", test.signature); err != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	client := chatbase.New("key")
+	key, _ := chatbase.NewEventProperty("event_key", "qrscene_123")
+	tests := []struct {
+		name            string
+		input           string
+		expectError     bool
+		expectedMessage *chatbase.Message
+		expectedEvent   *chatbase.Event
+	}{
+		{
+			"text",
+			`<xml><ToUserName><![CDATA[account]]></ToUserName><FromUserName><![CDATA[user]]></FromUserName><CreateTime>1348831860</CreateTime><MsgType><![CDATA[text]]></MsgType><Content><![CDATA[Hello]]></Content><MsgId>1234567890123456</MsgId></xml>`,
+			false,
+			&chatbase.Message{APIKey: "key", Type: chatbase.UserType, UserID: "user", TimeStamp: 1348831860000, Platform: "WeChat", Message: "Hello", SessionID: "user"},
+			nil,
+		},
+		{
+			"voice",
+			`<xml><ToUserName>account</ToUserName><FromUserName>user</FromUserName><CreateTime>1348831860</CreateTime><MsgType>voice</MsgType><Recognition>Hello</Recognition></xml>`,
+			false,
+			&chatbase.Message{APIKey: "key", Type: chatbase.UserType, UserID: "user", TimeStamp: 1348831860000, Platform: "WeChat", Message: "Hello", SessionID: "user"},
+			nil,
+		},
+		{
+			"subscribe",
+			`<xml><ToUserName>account</ToUserName><FromUserName>user</FromUserName><CreateTime>1348831860</CreateTime><MsgType>event</MsgType><Event>subscribe</Event><EventKey>qrscene_123</EventKey></xml>`,
+			false,
+			nil,
+			&chatbase.Event{APIKey: "key", UserID: "user", Intent: SubscribeEvent, TimeStamp: 1348831860000, Platform: "WeChat", Properties: []chatbase.EventProperty{key}},
+		},
+		{
+			"unsubscribe",
+			`<xml><ToUserName>account</ToUserName><FromUserName>user</FromUserName><CreateTime>1348831860</CreateTime><MsgType>event</MsgType><Event>unsubscribe</Event></xml>`,
+			false,
+			nil,
+			&chatbase.Event{APIKey: "key", UserID: "user", Intent: UnsubscribeEvent, TimeStamp: 1348831860000, Platform: "WeChat"},
+		},
+		{"location", `<xml><FromUserName>user</FromUserName><MsgType>event</MsgType><Event>LOCATION</Event></xml>`, true, nil, nil},
+		{"image", `<xml><FromUserName>user</FromUserName><MsgType>image</MsgType></xml>`, true, nil, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var m Message
+			if err := xml.Unmarshal([]byte(test.input), &m); err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			message, event, err := Convert(client, &m)
+			if test.expectError != (err != nil) {
+				t.Errorf("Unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(test.expectedMessage, message) {
+				t.Errorf("Expected %#v, got %#v", test.expectedMessage, message)
+			}
+			if !reflect.DeepEqual(test.expectedEvent, event) {
+				t.Errorf("Expected %#v, got %#v", test.expectedEvent, event)
+			}
+		})
+	}
+}
+
+func TestAgentMessage(t *testing.T) {
+	client := chatbase.New("key")
+	tests := []struct {
+		name     string
+		reply    *Message
+		expected *chatbase.Message
+	}{
+		{
+			"text",
+			&Message{ToUserName: "user", FromUserName: "account", CreateTime: 1348831861, MsgType: TextMessage, Content: "Hi"},
+			&chatbase.Message{APIKey: "key", Type: chatbase.AgentType, UserID: "user", TimeStamp: 1348831861000, Platform: "WeChat", Message: "Hi", SessionID: "user"},
+		},
+		{
+			"news",
+			&Message{ToUserName: "user", FromUserName: "account", CreateTime: 1348831861, MsgType: "news"},
+			&chatbase.Message{APIKey: "key", Type: chatbase.AgentType, UserID: "user", TimeStamp: 1348831861000, Platform: "WeChat", Message: "news", SessionID: "user"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if m := AgentMessage(client, test.reply); !reflect.DeepEqual(test.expected, m) {
+				t.Errorf("Expected %#v, got %#v", test.expected, m)
+			}
+		})
+	}
+}